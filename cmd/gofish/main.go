@@ -0,0 +1,182 @@
+// Package main is the gofish command, to look at a pond without serving it.
+//
+//	gofish routes [-json] <dir>
+//	gofish survey <dir>
+//	gofish export [-flow <dir>]... <dir> <out>
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Isaac799/go-fish/pkg/aquatic"
+)
+
+var errUsage = errors.New("usage: gofish routes [-json] <dir> | gofish survey <dir> | gofish export [-flow <dir>]... <dir> <out>")
+
+// dirs is a flag given more than once, such as `-flow asset -flow icons`
+type dirs []string
+
+func (d *dirs) String() string {
+	return strings.Join(*d, ",")
+}
+
+func (d *dirs) Set(dir string) error {
+	*d = append(*d, dir)
+	return nil
+}
+
+// parseArgs parses flags that may come before, after, or between
+// the other args, giving back the other args
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	rest := []string{}
+	for {
+		err := flags.Parse(args)
+		if err != nil {
+			return nil, errUsage
+		}
+		if flags.NArg() == 0 {
+			return rest, nil
+		}
+		rest = append(rest, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "routes":
+		return routes(args[1:], out)
+	case "survey":
+		return survey(args[1:], out)
+	case "export":
+		return export(args[1:], out)
+	}
+	return errUsage
+}
+
+// routes prints the routes of the pond in a dir, relative to the
+// working dir. Licenses are given in go, so only pond files are known.
+func routes(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("routes", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	asJSON := flags.Bool("json", false, "print routes as json")
+
+	rest, err := parseArgs(flags, args)
+	if err != nil || len(rest) != 1 {
+		return errUsage
+	}
+
+	pond, err := aquatic.NewPond[any, any](rest[0], aquatic.NewPondOptions{})
+	if err != nil {
+		return err
+	}
+
+	found := aquatic.Routes(&pond)
+
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(found)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "methods\tpattern\tkind\tfile\tparams\tlicenses\thash")
+	for _, route := range found {
+		methods := strings.Join(route.Methods, ",")
+		if len(methods) == 0 {
+			methods = "*"
+		}
+		params := make([]string, len(route.PathParams))
+		for i, name := range route.PathParams {
+			params[i] = name
+			if typ, typed := route.ParamTypes[name]; typed {
+				params[i] += "@" + typ
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			methods,
+			route.Pattern,
+			route.Kind,
+			route.File,
+			strings.Join(params, ","),
+			route.Licenses,
+			route.Hash,
+		)
+	}
+	return tw.Flush()
+}
+
+// survey looks over the pond in a dir for anything that would
+// stop lines being cast, such as conflicting patterns
+func survey(args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	pond, err := aquatic.NewPond[any, any](args[0], aquatic.NewPondOptions{})
+	if err != nil {
+		return err
+	}
+
+	err = aquatic.Survey(&pond)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "pond is fit for fishing")
+	return nil
+}
+
+// export writes the pond in a dir to another as a static site. Bait is
+// given in go, so pages are rendered with an empty local and those with
+// path values are not exported. Asset ponds given with -flow flow into
+// the pond first, so `asset` can find their fish.
+func export(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flows := dirs{}
+	flags.Var(&flows, "flow", "dir of an asset pond that flows into the pond")
+
+	rest, err := parseArgs(flags, args)
+	if err != nil || len(rest) != 2 {
+		return errUsage
+	}
+	dir, outDir := rest[0], rest[1]
+
+	pond, err := aquatic.NewPond[any, map[string]any](dir, aquatic.NewPondOptions{})
+	if err != nil {
+		return err
+	}
+	for _, flowDir := range flows {
+		assetPond, err := aquatic.NewPond[any, map[string]any](flowDir, aquatic.NewPondOptions{GlobalSmallFish: true})
+		if err != nil {
+			return err
+		}
+		aquatic.FlowsInto(&assetPond, &pond)
+	}
+
+	err = aquatic.Export(&pond, outDir, aquatic.ExportOptions{})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "exported to", outDir)
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Isaac799/go-fish/pkg/aquatic"
+)
+
+const mockDir = "../../pkg/aquatic/testdata/pond"
+
+func TestRoutes_Table(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := run([]string{"routes", mockDir}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "/user/{id}") {
+		t.Fatal(out.String())
+	}
+}
+
+func TestRoutes_Absolute(t *testing.T) {
+	abs, err := filepath.Abs(mockDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	err = run([]string{"routes", abs}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "/user/{id}") {
+		t.Fatal(out.String())
+	}
+}
+
+func TestRoutes_JSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := run([]string{"routes", mockDir, "-json"}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := []aquatic.Route{}
+	err = json.Unmarshal(out.Bytes(), &found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) == 0 {
+		t.Fatal(out.String())
+	}
+}
+
+func TestSurvey(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := run([]string{"survey", mockDir}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	err := run([]string{"export", mockDir, dir}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"index.html", "user/index.html"} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExport_Flow(t *testing.T) {
+	tmp := t.TempDir()
+	files := map[string]string{
+		"ux/_layout.html":       `<link rel="icon" href="{{ asset "image/smile.png" }}">{{ .Body }}`,
+		"ux/ux.html":            "<p>home</p>",
+		"asset/image/smile.png": "smile",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmp, name)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	ux, asset, dir := filepath.Join(tmp, "ux"), filepath.Join(tmp, "asset"), filepath.Join(tmp, "out")
+
+	err := run([]string{"export", ux, dir}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("asset found without its pond")
+	}
+
+	err = run([]string{"export", "-flow", asset, ux, dir}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "/image/smile.") || !strings.Contains(string(b), "<p>home</p>") {
+		t.Fatal(string(b))
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	for _, args := range [][]string{{}, {"nope"}, {"routes"}, {"routes", "-yaml", mockDir}, {"survey"}, {"export", mockDir}, {"export", "-flow", mockDir}} {
+		err := run(args, &bytes.Buffer{})
+		if !errors.Is(err, errUsage) {
+			t.Fatal(args, err)
+		}
+	}
+}
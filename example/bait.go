@@ -3,11 +3,13 @@ package main
 import (
 	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
 	"strconv"
 
+	"github.com/Isaac799/go-fish/pkg/aquatic"
 	"github.com/Isaac799/go-fish/pkg/bridge"
 	"github.com/Isaac799/go-fish/pkg/bridge/table"
 )
@@ -127,38 +129,52 @@ func dragDrop(r *http.Request) *fishData {
 	return &data
 }
 
-func queriedSeason(r *http.Request) *fishData {
+var seasons = map[string]bool{
+	"":       true,
+	"spring": true,
+	"summer": true,
+	"fall":   true,
+	"winter": true,
+}
+
+func queriedSeason(_ http.ResponseWriter, r *http.Request) (*fishData, error) {
 	data := fishData{}
 	season, ok := r.Context().Value(queryCtxKey).(string)
 	if !ok {
-		return nil
+		return nil, errors.New("season query was not given")
+	}
+	if !seasons[season] {
+		return nil, fmt.Errorf("no season %q: %w", season, aquatic.ErrBadRequest)
 	}
 	data.Season = season
-	return &data
+	return &data, nil
 }
 
-func userInfo(r *http.Request) *fishData {
+func userInfo(w http.ResponseWriter, r *http.Request) (*fishData, error) {
 	data := fishData{}
 	user, ok := r.Context().Value(userCtxKey).(user)
 	if !ok {
-		return nil
+		return nil, aquatic.ErrNotFound
 	}
+	// bait can set headers before the template is executed
+	w.Header().Set("X-User-ID", strconv.Itoa(user.ID))
 	data.User = &user
-	return &data
+	return &data, nil
 }
 
-func tableInfo(r *http.Request) *fishData {
-	fd := fishData{}
-
-	fd = fishData{Table: buildStatefulTable(r)}
-	return &fd
+func tableInfo(_ http.ResponseWriter, r *http.Request) (*fishData, error) {
+	tbl, err := buildStatefulTable(r)
+	if err != nil {
+		return nil, err
+	}
+	return &fishData{Table: tbl}, nil
 }
 
 // buildStatefulTable is so cool. 3 main parts
 //  1. Define the element
 //  2. Populate it (based on request form -  name:name attributes align, ignoring mismatches)
 //  3. Modify it based on its values
-func buildStatefulTable(r *http.Request) *bridge.HTMLElement {
+func buildStatefulTable(r *http.Request) (*bridge.HTMLElement, error) {
 	// CSV column identifiers
 	const (
 		ColID = iota
@@ -182,8 +198,7 @@ func buildStatefulTable(r *http.Request) *bridge.HTMLElement {
 
 	err := tbl.Modify(defineSort, definePagination, defineFilter)
 	if err != nil {
-		fmt.Print(err)
-		return nil
+		return nil, err
 	}
 
 	// 2: Populating the element form request
@@ -195,8 +210,7 @@ func buildStatefulTable(r *http.Request) *bridge.HTMLElement {
 
 	constraints, err := tbl.Constraints()
 	if err != nil {
-		fmt.Print(err)
-		return nil
+		return nil, err
 	}
 
 	// allows compile
@@ -230,15 +244,13 @@ func buildStatefulTable(r *http.Request) *bridge.HTMLElement {
 
 	err = tbl.Modify(modifySort, modifyPagination)
 	if err != nil {
-		fmt.Print(err)
-		return nil
+		return nil, err
 	}
 
 	err = tbl.SetData(records)
 	if err != nil {
-		fmt.Print(err)
-		return nil
+		return nil, err
 	}
 
-	return tbl.El
+	return tbl.El, nil
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"net/http"
 
+	"github.com/Isaac799/go-fish/pkg/aquatic"
 	"github.com/Isaac799/go-fish/pkg/bridge"
 )
 
@@ -22,6 +23,41 @@ func exampleFormBait(_ *http.Request) *fishData {
 	return &data
 }
 
+// exampleFormAction validates a submitted form. If any input is
+// invalid the form is rendered again with what was submitted,
+// otherwise the form is saved and the browser sent back.
+func exampleFormAction(_ http.ResponseWriter, r *http.Request) (aquatic.Haul[*fishData], error) {
+	form := exampleForm()
+	form.FormFill(r)
+
+	invalid := false
+	for _, input := range form.FindAll(bridge.LikeInput) {
+		if input.Attributes["type"] == bridge.InputKindHidden {
+			// set by us, not the user
+			continue
+		}
+		valid, err := input.Validate()
+		if err != nil {
+			// not every kind of input can be validated yet
+			continue
+		}
+		if !valid {
+			invalid = true
+			input.Attributes["aria-invalid"] = "true"
+		}
+	}
+
+	if invalid {
+		return aquatic.Haul[*fishData]{
+			Local:  &fishData{Form: &form},
+			Status: http.StatusUnprocessableEntity,
+		}, nil
+	}
+
+	// saving would happen here
+	return aquatic.Haul[*fishData]{Redirect: "/form"}, nil
+}
+
 func exampleForm() bridge.HTMLElement {
 	form := bridge.NewHTMLElement("form")
 	form.Children = make([]bridge.HTMLElement, 12)
@@ -78,7 +114,8 @@ func exampleForm() bridge.HTMLElement {
 	form.Children[11] = elCheckbox
 
 	form.EnsureAttributes()
-	form.Attributes["action"] = "/submit/test"
+	form.Attributes["action"] = "/form"
+	form.Attributes["method"] = "post"
 
 	submit := bridge.NewHTMLElement("button")
 	submit.EnsureAttributes()
@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"log/slog"
 	"net/http"
-	"strconv"
 	"time"
+
+	"github.com/Isaac799/go-fish/pkg/aquatic"
 )
 
 type contextKey string
@@ -20,6 +22,8 @@ const (
 	left  = "left"
 )
 
+var errUserNotFound = errors.New("user not found")
+
 var userDB = map[int]user{
 	1: {
 		ID:        1,
@@ -40,23 +44,15 @@ var userDB = map[int]user{
 
 func requireUser(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		id := r.PathValue("id")
-		if len(id) == 0 {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("a user id is required"))
-			return
-		}
-		i, err := strconv.Atoi(id)
+		id, err := aquatic.Param[int](r, "id")
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("user id must be an integer"))
+			aquatic.Snagged(w, r, http.StatusBadRequest, err)
 			return
 		}
 
-		u, exists := userDB[i]
+		u, exists := userDB[id]
 		if !exists {
-			w.WriteHeader(http.StatusNotFound)
-			w.Write([]byte("user not found"))
+			aquatic.Snagged(w, r, http.StatusNotFound, errUserNotFound)
 			return
 		}
 
@@ -75,7 +71,7 @@ func optionQuery(next http.Handler) http.Handler {
 
 func visitorLog(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Printf("visited %s\n", time.Now().Format(time.RFC1123))
+		logger.Info("visited", slog.String("path", r.URL.Path), slog.String("at", time.Now().Format(time.RFC1123)))
 		next.ServeHTTP(w, r)
 	})
 }
@@ -2,8 +2,9 @@
 package main
 
 import (
-	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
 	"regexp"
 
 	"github.com/Isaac799/go-fish/pkg/aquatic"
@@ -11,14 +12,27 @@ import (
 
 type globalData struct{}
 
+var logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
 func setupPond[T, K any]() aquatic.Pond[T, K] {
-	config := aquatic.NewPondOptions{Licenses: []aquatic.License{visitorLog}}
+	config := aquatic.NewPondOptions{
+		Licenses: []aquatic.License{visitorLog},
+		Logger:   logger,
+		// this is an example, so edits show up without a restart
+		HotReload:        true,
+		HotReloadBrowser: true,
+		// the head is sent before bait, so styles load while it is caught
+		StreamHead: true,
+	}
 	uxPond, err := aquatic.NewPond[T, K]("ux", config)
 	if err != nil {
 		panic(err)
 	}
 
-	assetPond, err := aquatic.NewPond[T, K]("asset", aquatic.NewPondOptions{GlobalSmallFish: true})
+	assetPond, err := aquatic.NewPond[T, K]("asset", aquatic.NewPondOptions{
+		GlobalSmallFish: true,
+		Logger:          logger,
+	})
 	if err != nil {
 		panic(err)
 	}
@@ -34,17 +48,23 @@ func main() {
 	stockFish := aquatic.Stock[globalData, *fishData]{
 		rx("season"): {
 			Licenses: []aquatic.License{optionQuery},
-			Bait:     queriedSeason,
+			Lure:     queriedSeason,
 		},
 		rx("user/.id"): {
-			Bait:     userInfo,
+			Lure:     userInfo,
 			Licenses: []aquatic.License{requireUser},
 		},
 		rx("/form"): {
-			Bait: exampleFormBait,
+			Bait:    exampleFormBait,
+			Methods: []string{http.MethodGet, http.MethodPost},
+			Action:  exampleFormAction,
 		},
 		rx("/table"): {
-			Bait: tableInfo,
+			Lure: tableInfo,
+		},
+		rx("table/_stateful_table"): {
+			// the table posts its form back to re-render
+			Methods: []string{http.MethodGet, http.MethodPost},
 		},
 		rx("drag-drop"): {
 			Bait: dragDrop,
@@ -60,6 +80,6 @@ func main() {
 	verbose := true
 	mux := aquatic.CastLines(&pond, verbose)
 
-	fmt.Println("gone fishing")
+	logger.Info("gone fishing", slog.String("addr", "localhost:8080"))
 	http.ListenAndServe("localhost:8080", mux)
 }
@@ -0,0 +1,176 @@
+package aquatic
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// defaultWakeVersions is how many versions of a clown or anchovy
+// fish are kept if the pond options do not say
+const defaultWakeVersions = 3
+
+// buoys mark the fingerprinted path of every clown and anchovy fish
+// in a pond, by its file path such as `image/smile.png`
+type buoys map[string]string
+
+// buoysOf marks the clown and anchovy fish lines are cast for
+func buoysOf[K any](fishes []*Fish[K]) buoys {
+	b := buoys{}
+	for _, f := range fishes {
+		if !fingerprinted(f) {
+			continue
+		}
+		b[strings.TrimPrefix(f.pattern, "/")] = fingerprint(f.pattern, f.hash)
+	}
+	return b
+}
+
+// Asset gives the fingerprinted path of a clown or anchovy fish, or a
+// breed that is fingerprinted, in a pond by its file path, so it can be
+// cached for good.
+//
+//	aquatic.Asset(&pond, "image/smile.png") // /image/smile.<hash>.png
+func Asset[T, K any](pond *Pond[T, K], name string) (string, error) {
+	b := pond.buoys.Load()
+	if b == nil {
+		// lines are not cast yet
+		pond.mu.RLock()
+		sortedFish, _ := gather(pond, noPuffers[K])
+		marked := buoysOf(sortedFish)
+		pond.mu.RUnlock()
+		b = &marked
+	}
+
+	hashed, exists := (*b)[strings.TrimPrefix(name, "/")]
+	if !exists {
+		return "", fmt.Errorf("%w: %s", ErrUnknownFish, name)
+	}
+	return hashed, nil
+}
+
+// assetTackle gives fingerprinted paths in a template from the latest
+// cast of a pond.
+//
+//	<img src="{{ asset "image/smile.png" }}">
+func assetTackle[T, K any](pond *Pond[T, K]) func(name string) (string, error) {
+	return func(name string) (string, error) {
+		return Asset(pond, name)
+	}
+}
+
+// adrift catches a fingerprint with a hash not known, such as one linked
+// by a page cached before a deploy, that another fish like the root tuna
+// would catch instead. The fish it is of is given as it is now, by the
+// handler in drifting for its pattern.
+func adrift(next http.Handler, drifting map[string]http.Handler) http.Handler {
+	if len(drifting) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if pattern, ok := unprinted(r.URL.Path); ok {
+			if h, exists := drifting[pattern]; exists {
+				h.ServeHTTP(w, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// unprinted gives the path a fingerprint is of, without its hash.
+// Such as `/style/simple.css` for `/style/simple.<hash>.css`.
+func unprinted(p string) (string, bool) {
+	ext := path.Ext(p)
+	stem := strings.TrimSuffix(p, ext)
+	hash := path.Ext(stem)
+	if len(hash) != 1+2*md5.Size || strings.Trim(hash[1:], "0123456789abcdef") != "" {
+		return "", false
+	}
+	return strings.TrimSuffix(stem, hash) + ext, true
+}
+
+// shell is a version of a clown or anchovy fish as it was when caught
+type shell[K any] struct {
+	fish    *Fish[K]
+	content []byte
+}
+
+// wake is what clown and anchovy fish leave behind as a pond is
+// restocked, so a page cached with an older version still finds it
+type wake[K any] struct {
+	mu     sync.Mutex
+	shells map[string][]shell[K]
+}
+
+func newWake[K any]() *wake[K] {
+	return &wake[K]{shells: map[string][]shell[K]{}}
+}
+
+// follow keeps the version of every clown and anchovy fish as it is now,
+// up to a number of versions per pattern. Gives back every version that
+// is no longer current.
+func (wk *wake[K]) follow(fishes []*Fish[K], versions int) []shell[K] {
+	wk.mu.Lock()
+	defer wk.mu.Unlock()
+
+	current := map[string]string{}
+	for _, f := range fishes {
+		if f.kind != FiskKindClown && f.kind != FiskKindAnchovy {
+			continue
+		}
+		current[f.pattern] = f.hash
+
+		shells := wk.shells[f.pattern]
+		known := false
+		for _, s := range shells {
+			if s.fish.hash == f.hash {
+				known = true
+				break
+			}
+		}
+		if known {
+			continue
+		}
+
+		// the file may have changed again since found,
+		// then it is kept with the next restock
+		b, err := fs.ReadFile(f.fsys, f.fsPath)
+		if err != nil || hashOf(b) != f.hash {
+			continue
+		}
+		shells = append(shells, shell[K]{fish: f, content: b})
+		if len(shells) > versions+1 {
+			shells = shells[len(shells)-versions-1:]
+		}
+		wk.shells[f.pattern] = shells
+	}
+
+	past := []shell[K]{}
+	for pattern, shells := range wk.shells {
+		for _, s := range shells {
+			if current[pattern] != s.fish.hash {
+				past = append(past, s)
+			}
+		}
+	}
+	return past
+}
+
+// handlerShell serves a version of a clown or anchovy fish as it was
+func handlerShell[K any](s shell[K]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", immutableCacheControl)
+		if tagged(w, r, etagOf(s.fish.hash), s.fish.modTime) {
+			return
+		}
+
+		w.Header().Set("Content-Type", s.fish.mime)
+		http.ServeContent(w, r, s.fish.fsPath, s.fish.modTime, bytes.NewReader(s.content))
+	}
+}
@@ -0,0 +1,154 @@
+package aquatic
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestAsset(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/ux.html":            {Data: []byte(`<img src="{{ asset "image/smile.png" }}">`)},
+		"ux/broken/broken.html": {Data: []byte(`<img src="{{ asset "image/frown.png" }}">`)},
+		"ux/site.css":           {Data: []byte(`body{}`)},
+		"ux/image/smile.png":    {Data: []byte("\x89PNG\r\n\x1a\n")},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{GlobalSmallFish: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	smile := "/image/smile." + hashOf(fsys["ux/image/smile.png"].Data) + ".png"
+	site := "/site." + hashOf(fsys["ux/site.css"].Data) + ".css"
+
+	// before and after lines are cast
+	for _, name := range []string{"image/smile.png", "/image/smile.png"} {
+		u, err := Asset(&pond, name)
+		if err != nil || u != smile {
+			t.Fatal(errUnexpectedValue, name, u, err)
+		}
+	}
+	mux := CastLines(&pond, false)
+	_, err = Asset(&pond, "image/frown.png")
+	if !errors.Is(err, ErrUnknownFish) {
+		t.Fatal(errUnexpectedValue, err)
+	}
+
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), `<img src="`+smile+`">`)
+	assertContains(t, w.Body.String(), `<link rel="stylesheet" href="`+site+`">`)
+
+	w = mockCatch(mux, http.MethodGet, "/broken")
+	assertStatus(t, w, http.StatusInternalServerError)
+
+	for _, target := range []string{smile, site} {
+		w = mockCatch(mux, http.MethodGet, target)
+		assertStatus(t, w, http.StatusOK)
+		if w.Header().Get("Cache-Control") != immutableCacheControl {
+			t.Fatal(errUnexpectedValue, target, w.Header().Get("Cache-Control"))
+		}
+	}
+
+	// by name it may change, and old version queries are not checked
+	w = mockCatch(mux, http.MethodGet, "/site.css?v=old")
+	assertStatus(t, w, http.StatusOK)
+	if w.Header().Get("Cache-Control") == immutableCacheControl {
+		t.Fatal(errUnexpectedValue, w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestAsset_Wake(t *testing.T) {
+	dir, rel := mockTempPond(t, map[string]string{
+		"pond.html": "<p>pond</p>",
+		"site.css":  "body{}",
+	})
+
+	pond, err := NewPond[mockGlobal, mockLocal](rel, NewPondOptions{
+		HotReload:         true,
+		HotReloadInterval: 10 * time.Millisecond,
+		WakeVersions:      1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Drain(&pond) })
+	mux := CastLines(&pond, false)
+
+	first := fingerprint("/site.css", hashOf([]byte("body{}")))
+	mockEventually(t, mux, first, http.StatusOK, "body{}")
+
+	mockWrite(t, filepath.Join(dir, "site.css"), "body{margin:0}")
+	second := fingerprint("/site.css", hashOf([]byte("body{margin:0}")))
+	// any fingerprint is given the file as it is now, so the restock
+	// is known by the page linking it
+	mockEventually(t, mux, "/", http.StatusOK, second)
+
+	// a page cached before the restock still finds its version
+	w := mockCatch(mux, http.MethodGet, first)
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), "body{}")
+	if w.Header().Get("Cache-Control") != immutableCacheControl {
+		t.Fatal(errUnexpectedValue, w.Header().Get("Cache-Control"))
+	}
+
+	// only so many versions are kept
+	mockWrite(t, filepath.Join(dir, "site.css"), "body{margin:0;padding:0}")
+	third := fingerprint("/site.css", hashOf([]byte("body{margin:0;padding:0}")))
+	mockEventually(t, mux, "/", http.StatusOK, third)
+	mockEventually(t, mux, second, http.StatusOK, "body{margin:0}")
+	w = mockCatch(mux, http.MethodGet, first)
+	assertNotContains(t, w.Body.String(), "body{}")
+	if w.Header().Get("Cache-Control") == immutableCacheControl {
+		t.Fatal(errUnexpectedValue, w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestAsset_Adrift(t *testing.T) {
+	// a tuna at the root catches everything, with `$` only the root
+	for _, root := range []string{"ux/ux.html", "ux/ux$.html"} {
+		fsys := fstest.MapFS{
+			root:                   {Data: []byte(`<p>home</p>`)},
+			"ux/user/user.id.html": {Data: []byte(`<p>user</p>`)},
+			"ux/site.css":          {Data: []byte(`body{}`)},
+			"ux/smile.png":         {Data: []byte("\x89PNG\r\n\x1a\nsmile")},
+			"ux/user/badge.png":    {Data: []byte("\x89PNG\r\n\x1a\nbadge")},
+		}
+		pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mux := CastLines(&pond, false)
+
+		// as linked by a page cached before a deploy
+		unknown := strings.Repeat("0", 32)
+		tests := []struct {
+			target string
+			mime   string
+			body   string
+		}{
+			{fingerprint("/site.css", unknown), "text/css; charset=utf-8", "body{}"},
+			{fingerprint("/smile.png", unknown), "image/png", "\x89PNG\r\n\x1a\nsmile"},
+			{fingerprint("/user/badge.png", unknown), "image/png", "\x89PNG\r\n\x1a\nbadge"},
+		}
+		for _, tt := range tests {
+			w := mockCatch(mux, http.MethodGet, tt.target)
+			assertStatus(t, w, http.StatusOK)
+			if w.Body.String() != tt.body || w.Header().Get("Content-Type") != tt.mime || w.Header().Get("Cache-Control") != "no-cache" {
+				t.Fatal(errUnexpectedValue, root, tt.target, w.Header(), w.Body.String())
+			}
+		}
+
+		// not a fingerprint of a fish, caught as before
+		w := mockCatch(mux, http.MethodGet, fingerprint("/nope.css", unknown))
+		if root == "ux/ux.html" {
+			assertContains(t, w.Body.String(), "<p>home</p>")
+		} else {
+			assertStatus(t, w, http.StatusNotFound)
+		}
+	}
+}
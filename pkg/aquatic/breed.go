@@ -0,0 +1,203 @@
+package aquatic
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Breed is a kind of fish a pond does not know by itself, such as
+// markdown rendered as a page, a manifest, or a download. Given to a
+// pond with its options, and checked in order before the kinds it knows.
+//
+//	aquatic.Breed{
+//		Name:     "Markdown",
+//		Classify: func(_, ext string) bool { return ext == ".md" },
+//		Reel:     renderMarkdown,
+//	}
+type Breed struct {
+	// Name is what the kind is called, such as in [Routes] and logs
+	Name string
+	// Classify is true for a file of the breed, by its mime type and
+	// extension. The mime type is guessed from the content if the
+	// extension does not say.
+	Classify func(mime, ext string) bool
+	// Reel gives what catches a fish of the breed. Called each time
+	// lines are cast, so work like rendering is done once. An error
+	// snags every catch with 500. The file is served as it is if nil.
+	Reel func(s Specimen) (http.Handler, error)
+	// Mime is the content type the file is served with if Reel is nil,
+	// in place of the one found. Such as `application/manifest+json`.
+	Mime string
+	// CacheControl is given to every response, unless the handler sets
+	// its own. `no-cache` if empty.
+	CacheControl string
+	// ETag gives responses the hash of the file as an etag, and a
+	// request that already has it is told it is not modified.
+	ETag bool
+	// Fingerprint has the fish caught by its fingerprinted path too,
+	// cached for good, and found by `asset` the same as clown fish.
+	// Previous versions are not kept on hot reload.
+	Fingerprint bool
+}
+
+// Specimen is a fish of a breed as it was found, given to its reel
+type Specimen struct {
+	// Pattern is what the fish is caught with, such as `/docs/intro.md`
+	Pattern string
+	// File is the path of the fish relative to its pond
+	File string
+	// Mime is the content type found for the file
+	Mime string
+	// Hash of the content of the fish
+	Hash string
+	// ModTime is when the fish last changed. Zero if its
+	// file system does not know, such as an embedded one.
+	ModTime time.Time
+
+	fsys   fs.FS
+	fsPath string
+}
+
+// Open opens the file of the fish, such as to stream it for each catch
+func (s Specimen) Open() (fs.File, error) {
+	return s.fsys.Open(s.fsPath)
+}
+
+// ReadFile reads the whole file of the fish
+func (s Specimen) ReadFile() ([]byte, error) {
+	return fs.ReadFile(s.fsys, s.fsPath)
+}
+
+// breedOf gives the first breed of a pond that classifies a file
+func breedOf[T, K any](pond *Pond[T, K], mime, ext string) *Breed {
+	for i := range pond.options.Breeds {
+		b := &pond.options.Breeds[i]
+		if b.Classify != nil && b.Classify(mime, ext) {
+			return b
+		}
+	}
+	return nil
+}
+
+// specimenOf gives what was found of a fish to its breed
+func specimenOf[K any](f *Fish[K]) Specimen {
+	return Specimen{
+		Pattern: f.pattern,
+		File:    f.scopedFilePath,
+		Mime:    f.mime,
+		Hash:    f.hash,
+		ModTime: f.modTime,
+		fsys:    f.fsys,
+		fsPath:  f.fsPath,
+	}
+}
+
+// kindName gives what the kind of a fish is called
+func kindName[K any](f *Fish[K]) string {
+	if f.kind == FishKindBreed && f.breed != nil {
+		return f.breed.Name
+	}
+	return fishKindStr[f.kind]
+}
+
+// fingerprinted is true for fish also caught by their fingerprint
+func fingerprinted[K any](f *Fish[K]) bool {
+	if f.kind == FishKindBreed {
+		return f.breed != nil && f.breed.Fingerprint
+	}
+	return f.kind == FiskKindClown || f.kind == FiskKindAnchovy
+}
+
+// handlerBreed catches a fish with its breed, giving
+// the response the caching rules of the breed first
+func handlerBreed[T, K any](f *Fish[K], pond *Pond[T, K]) http.Handler {
+	breed := f.breed
+	if breed == nil {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Snagged(w, r, http.StatusInternalServerError, ErrInvalidExtension)
+		})
+	}
+
+	var next http.Handler = handlerSpecimen(f, pond, breed.Mime)
+	if breed.Reel != nil {
+		reeled, err := breed.Reel(specimenOf(f))
+		if err != nil {
+			pond.options.Logger.Error("cannot reel breed", append(fishAttrs(f), slog.Any("err", err))...)
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Snagged(w, r, http.StatusInternalServerError, err)
+			})
+		}
+		next = reeled
+	}
+
+	cacheControl := breed.CacheControl
+	if len(cacheControl) == 0 {
+		cacheControl = "no-cache"
+	}
+
+	printed := fingerprint(f.pattern, f.hash)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w = &breedWriter{ResponseWriter: w}
+		if r.URL.Path == printed {
+			w.Header().Set("Cache-Control", immutableCacheControl)
+		} else {
+			// by name, or a fingerprint not known, see adrift
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		if breed.ETag && tagged(w, r, etagOf(f.hash), f.modTime) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// breedWriter drops the caching headers set for a breed from a catch
+// that is not a success, such as a snag, so it is not cached as the fish
+type breedWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+func (b *breedWriter) Write(p []byte) (int, error) {
+	b.wrote = true
+	return b.ResponseWriter.Write(p)
+}
+
+func (b *breedWriter) WriteHeader(status int) {
+	if !b.wrote && status >= 300 && status != http.StatusNotModified {
+		h := b.Header()
+		h.Del("Cache-Control")
+		h.Del("ETag")
+		h.Del("Last-Modified")
+	}
+	b.wrote = true
+	b.ResponseWriter.WriteHeader(status)
+}
+
+func (b *breedWriter) Unwrap() http.ResponseWriter {
+	return b.ResponseWriter
+}
+
+// handlerSpecimen serves the file of a fish as it is
+func handlerSpecimen[T, K any](f *Fish[K], pond *Pond[T, K], mime string) http.HandlerFunc {
+	if len(mime) == 0 {
+		mime = f.mime
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		file, err := f.fsys.Open(f.fsPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			Snagged(w, r, http.StatusNotFound, err)
+			return
+		}
+		if err != nil {
+			Snagged(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		defer file.Close()
+		w.Header().Set("Content-Type", mime)
+		stream(w, r, file, f, pond)
+	}
+}
@@ -0,0 +1,204 @@
+package aquatic
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+var errMockReel = errors.New("cannot reel")
+
+// mockBreeds are a markdown breed rendered once as a page, a manifest
+// served as it is, and a download caught by its fingerprint too
+func mockBreeds() []Breed {
+	return []Breed{
+		{
+			Name:     "Markdown",
+			Classify: func(_, ext string) bool { return ext == ".md" },
+			Reel: func(s Specimen) (http.Handler, error) {
+				b, err := s.ReadFile()
+				if err != nil {
+					return nil, err
+				}
+				if bytes.HasPrefix(b, []byte("!")) {
+					return nil, errMockReel
+				}
+				page := "<h1>" + template.HTMLEscapeString(string(bytes.TrimPrefix(b, []byte("# ")))) + "</h1>"
+				return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.Header().Set("Content-Type", "text/html")
+					w.Write([]byte(page))
+				}), nil
+			},
+			ETag: true,
+		},
+		{
+			Name:         "Manifest",
+			Classify:     func(_, ext string) bool { return ext == ".webmanifest" },
+			Mime:         "application/manifest+json",
+			CacheControl: "public, max-age=60",
+		},
+		{
+			Name:        "Download",
+			Classify:    func(mime, _ string) bool { return mime == "application/pdf" },
+			Fingerprint: true,
+			ETag:        true,
+		},
+	}
+}
+
+func TestBreed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/ux.html":               {Data: []byte(`<a href="{{ asset "menu.pdf" }}">menu</a>`)},
+		"ux/docs/intro.md":         {Data: []byte(`# Hello <fish>`)},
+		"ux/docs/broken.md":        {Data: []byte(`!`)},
+		"ux/site.webmanifest":      {Data: []byte(`{"name":"pond"}`)},
+		"ux/menu.pdf":              {Data: []byte("%PDF-1.4 menu")},
+		"ux/data.json":             {Data: []byte(`{}`)},
+		"ux/docs/docs.html":        {Data: []byte(`<p>docs</p>`)},
+		"ux/docs/_not_a_breed.css": {Data: []byte(`p{}`)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{Breeds: mockBreeds()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := CastLines(&pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/docs/intro.md")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), "<h1>Hello &lt;fish&gt;</h1>")
+	etag := etagOf(hashOf(fsys["ux/docs/intro.md"].Data))
+	if w.Header().Get("ETag") != etag || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+	w = mockRevalidate(mux, http.MethodGet, "/docs/intro.md", map[string]string{"If-None-Match": etag})
+	assertStatus(t, w, http.StatusNotModified)
+
+	w = mockCatch(mux, http.MethodGet, "/docs/broken.md")
+	assertStatus(t, w, http.StatusInternalServerError)
+
+	w = mockCatch(mux, http.MethodGet, "/site.webmanifest")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), `{"name":"pond"}`)
+	if w.Header().Get("Content-Type") != "application/manifest+json" ||
+		w.Header().Get("Cache-Control") != "public, max-age=60" ||
+		len(w.Header().Get("ETag")) > 0 {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+
+	hashed := fingerprint("/menu.pdf", hashOf(fsys["ux/menu.pdf"].Data))
+	w = mockCatch(mux, http.MethodGet, "/")
+	assertContains(t, w.Body.String(), `href="`+hashed+`"`)
+	w = mockCatch(mux, http.MethodGet, hashed)
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), "%PDF-1.4 menu")
+	if w.Header().Get("Cache-Control") != immutableCacheControl || w.Header().Get("Content-Type") != "application/pdf" {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+
+	// still dropped without a breed
+	w = mockCatch(mux, http.MethodGet, "/data.json")
+	assertNotContains(t, w.Body.String(), "{}")
+
+	kinds := map[string]string{}
+	for _, route := range Routes(&pond) {
+		kinds[route.File] = route.Kind
+	}
+	expected := map[string]string{
+		"/docs/intro.md":         "Markdown",
+		"/site.webmanifest":      "Manifest",
+		"/menu.pdf":              "Download",
+		"/docs/_not_a_breed.css": "Clown",
+	}
+	for file, kind := range expected {
+		if kinds[file] != kind {
+			t.Fatal(errUnexpectedValue, file, kinds[file])
+		}
+	}
+	if _, exists := kinds["/data.json"]; exists {
+		t.Fatal(errUnexpectedValue, kinds)
+	}
+}
+
+func TestBreed_Gone(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/ux.html":  {Data: []byte(`<p>home</p>`)},
+		"ux/menu.pdf": {Data: []byte("%PDF-1.4 menu")},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{Breeds: mockBreeds()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := CastLines(&pond, false)
+	hashed := fingerprint("/menu.pdf", hashOf(fsys["ux/menu.pdf"].Data))
+	delete(fsys, "ux/menu.pdf")
+
+	for _, target := range []string{"/menu.pdf", hashed} {
+		w := mockCatch(mux, http.MethodGet, target)
+		assertStatus(t, w, http.StatusNotFound)
+		h := w.Header()
+		if len(h.Get("Cache-Control")) > 0 || len(h.Get("ETag")) > 0 || len(h.Get("Last-Modified")) > 0 || h.Get("Content-Type") == "application/pdf" {
+			t.Fatal(errUnexpectedValue, target, h)
+		}
+	}
+}
+
+func TestBreed_FirstWins(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/ux.html":  {Data: []byte(`<p>home</p>`)},
+		"ux/site.css": {Data: []byte(`body{}`)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{
+		Breeds: []Breed{
+			{Name: "Plain", Classify: func(mime, _ string) bool { return mime == "text/css; charset=utf-8" }, Mime: "text/plain"},
+			{Name: "Never", Classify: func(_, ext string) bool { return ext == ".css" }},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := CastLines(&pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/site.css")
+	assertStatus(t, w, http.StatusOK)
+	if w.Header().Get("Content-Type") != "text/plain" {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+}
+
+func TestBreed_Export(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/ux.html":        {Data: []byte(`<p>home</p>`)},
+		"ux/docs/docs.html": {Data: []byte(`<p>docs</p>`)},
+		"ux/docs/intro.md":  {Data: []byte(`# Hello`)},
+		"ux/menu.pdf":       {Data: []byte("%PDF-1.4 menu")},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{Breeds: mockBreeds()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := Export(&pond, dir, ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"docs/intro.md": "<h1>Hello</h1>",
+		"menu.pdf":      "%PDF-1.4 menu",
+		fingerprint("menu.pdf", hashOf(fsys["ux/menu.pdf"].Data)): "%PDF-1.4 menu",
+	}
+	for name, content := range expected {
+		b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err, name)
+		}
+		if string(b) != content {
+			t.Fatal(errUnexpectedValue, name, string(b))
+		}
+	}
+}
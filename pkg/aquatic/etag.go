@@ -0,0 +1,80 @@
+package aquatic
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// etagOf gives a strong etag for a hash of content
+func etagOf(hash string) string {
+	return `"` + hash + `"`
+}
+
+// unchanged tells if a request already has what would be sent, by its
+// If-None-Match etags or else its If-Modified-Since time. Only for
+// requests just looking, others must be caught.
+func unchanged(r *http.Request, etag string, modTime time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if match := r.Header.Get("If-None-Match"); len(match) > 0 {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" {
+				return true
+			}
+			// weak comparison, as If-None-Match is
+			if strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if modTime.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !modTime.Truncate(time.Second).After(since)
+}
+
+// notModified tells a browser what it has is still good
+func notModified(w http.ResponseWriter) {
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// tagged gives a response its etag and modified time,
+// and tells if the request already has it
+func tagged(w http.ResponseWriter, r *http.Request, etag string, modTime time.Time) bool {
+	w.Header().Set("ETag", etag)
+	if !modTime.IsZero() {
+		w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+	if !unchanged(r, etag, modTime) {
+		return false
+	}
+	notModified(w)
+	return true
+}
+
+// pageTagged gives a rendered tuna or sardine a strong etag of its
+// bytes if the pond wants, and tells if the request already has it.
+// Pages must be revalidated, since bait can change without the fish.
+func pageTagged[T, K any](w http.ResponseWriter, r *http.Request, pond *Pond[T, K], haul Haul[K], b []byte) bool {
+	if !pond.options.PageETags {
+		return false
+	}
+	if haul.Status != 0 && haul.Status != http.StatusOK {
+		return false
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return tagged(w, r, etagOf(hashOf(b)), time.Time{})
+}
@@ -0,0 +1,137 @@
+package aquatic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// mockRevalidate catches a fish with the headers a browser
+// revalidating what it has would send
+func mockRevalidate(mux http.Handler, method, target string, headers map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, target, nil)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	mux.ServeHTTP(w, r)
+	return w
+}
+
+func TestETag_Asset(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"ux/ux.html":  {Data: []byte(`<p>home</p>`)},
+		"ux/site.css": {Data: []byte(`body{}`), ModTime: modTime},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := CastLines(&pond, false)
+
+	etag := etagOf(hashOf([]byte(`body{}`)))
+	for _, target := range []string{"/site.css", fingerprint("/site.css", hashOf([]byte(`body{}`)))} {
+		w := mockCatch(mux, http.MethodGet, target)
+		assertStatus(t, w, http.StatusOK)
+		if w.Header().Get("ETag") != etag || w.Header().Get("Last-Modified") != modTime.Format(http.TimeFormat) {
+			t.Fatal(errUnexpectedValue, w.Header())
+		}
+
+		tests := []struct {
+			headers map[string]string
+			status  int
+		}{
+			{map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+			{map[string]string{"If-None-Match": `"old", W/` + etag}, http.StatusNotModified},
+			{map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+			{map[string]string{"If-None-Match": `"old"`}, http.StatusOK},
+			{map[string]string{"If-Modified-Since": modTime.Format(http.TimeFormat)}, http.StatusNotModified},
+			{map[string]string{"If-Modified-Since": modTime.Add(-time.Hour).Format(http.TimeFormat)}, http.StatusOK},
+			// etags win over time
+			{map[string]string{"If-None-Match": `"old"`, "If-Modified-Since": modTime.Format(http.TimeFormat)}, http.StatusOK},
+		}
+		for _, tt := range tests {
+			w := mockRevalidate(mux, http.MethodGet, target, tt.headers)
+			assertStatus(t, w, tt.status)
+			if tt.status == http.StatusNotModified && (w.Body.Len() > 0 || len(w.Header().Get("Cache-Control")) == 0) {
+				t.Fatal(errUnexpectedValue, tt.headers, w.Header())
+			}
+		}
+	}
+}
+
+func TestETag_AssetGone(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/ux.html":  {Data: []byte(`<p>home</p>`)},
+		"ux/site.css": {Data: []byte(`body{}`)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := CastLines(&pond, false)
+	delete(fsys, "ux/site.css")
+
+	for _, target := range []string{"/site.css", fingerprint("/site.css", hashOf([]byte(`body{}`)))} {
+		w := mockCatch(mux, http.MethodGet, target)
+		assertStatus(t, w, http.StatusNotFound)
+		h := w.Header()
+		if len(h.Get("Cache-Control")) > 0 || len(h.Get("ETag")) > 0 || len(h.Get("Last-Modified")) > 0 || h.Get("Content-Type") == "text/css; charset=utf-8" {
+			t.Fatal(errUnexpectedValue, target, h)
+		}
+	}
+}
+
+func TestETag_Page(t *testing.T) {
+	name := "Sally"
+	pond, err := NewPond[mockGlobal, mockLocal]("testdata/pond", NewPondOptions{PageETags: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	StockPond(&pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile(".html"): {
+			Bait: func(_ *http.Request) mockLocal {
+				return mockLocal{Name: name}
+			},
+		},
+	})
+	mux := CastLines(&pond, false)
+
+	for _, target := range []string{"/", "/user/_card"} {
+		w := mockCatch(mux, http.MethodGet, target)
+		assertStatus(t, w, http.StatusOK)
+		etag := w.Header().Get("ETag")
+		if etag != etagOf(hashOf(w.Body.Bytes())) || w.Header().Get("Cache-Control") != "no-cache" {
+			t.Fatal(errUnexpectedValue, target, w.Header())
+		}
+
+		w = mockRevalidate(mux, http.MethodGet, target, map[string]string{"If-None-Match": etag})
+		assertStatus(t, w, http.StatusNotModified)
+
+		// only requests just looking are not modified
+		w = mockRevalidate(mux, http.MethodPost, target, map[string]string{"If-None-Match": etag})
+		assertStatus(t, w, http.StatusOK)
+
+		// new bait, new page
+		name = "Bob"
+		w = mockRevalidate(mux, http.MethodGet, target, map[string]string{"If-None-Match": etag})
+		assertStatus(t, w, http.StatusOK)
+		assertContains(t, w.Body.String(), "Bob")
+		name = "Sally"
+	}
+}
+
+func TestETag_PageOff(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{Name: "Sally"})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusOK)
+	if len(w.Header().Get("ETag")) > 0 || w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+}
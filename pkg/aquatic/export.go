@@ -0,0 +1,220 @@
+package aquatic
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+)
+
+var (
+	// ErrExportStatus is given when a page could not be exported since it was not ok
+	ErrExportStatus = errors.New("page was not ok")
+	// ErrExportPath is given when a page would be written outside the export dir
+	ErrExportPath = errors.New("page would be outside export dir")
+)
+
+// ExportOptions decide how a pond is exported as a static site
+type ExportOptions struct {
+	// Enumerate gives the path values a tuna with path values is exported
+	// with, one page for each. Tuna with path values are skipped without it.
+	Enumerate func(route Route) []map[string]string
+	// Host is given to requests made to render pages. example.com if empty.
+	Host string
+}
+
+// exportRecorder keeps what a fish wrote when caught for export
+type exportRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (e *exportRecorder) Header() http.Header {
+	return e.header
+}
+
+func (e *exportRecorder) Write(b []byte) (int, error) {
+	if e.status == 0 {
+		e.status = http.StatusOK
+	}
+	return e.body.Write(b)
+}
+
+func (e *exportRecorder) WriteHeader(status int) {
+	if e.status == 0 {
+		e.status = status
+	}
+}
+
+// Export renders a pond to a dir as a static site. Every tuna caught with
+// GET is rendered with its bait to an `index.html` mirroring its pattern.
+// Clown and anchovy fish are copied with their hash in their name, as
+// bobbers link them. Anchovy fish are copied with their own name too,
+// since templates may link them by name instead of as an asset. Fish
+// of a breed are caught, and what their breed gives written by name.
+func Export[T, K any](pond *Pond[T, K], dir string, options ExportOptions) error {
+	if len(options.Host) == 0 {
+		options.Host = "example.com"
+	}
+
+	mux, err := castLines(pond, false)
+	if err != nil {
+		return err
+	}
+
+	pond.mu.RLock()
+	sortedFish, _ := gather(pond, noPuffers[K])
+	pond.mu.RUnlock()
+
+	errs := []error{}
+
+	for _, f := range sortedFish {
+		if f.kind == FishKindBreed {
+			errs = append(errs, exportBreed(mux, dir, f, options.Host))
+			continue
+		}
+		if f.kind != FiskKindClown && f.kind != FiskKindAnchovy {
+			continue
+		}
+		b, err := fs.ReadFile(f.fsys, f.fsPath)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, exportFile(dir, fingerprint(f.pattern, f.hash), b))
+		if f.kind == FiskKindAnchovy {
+			errs = append(errs, exportFile(dir, f.pattern, b))
+		}
+	}
+
+	for _, route := range Routes(pond) {
+		if route.Kind != fishKindStr[FishKindTuna] {
+			continue
+		}
+		if len(route.Methods) > 0 && !slices.Contains(route.Methods, http.MethodGet) {
+			continue
+		}
+
+		// an exact pattern is still filled, so `/{$}` is `/`
+		p, _ := fill(route.Pattern, nil)
+		paths := []string{p}
+		if len(route.PathParams) > 0 {
+			paths = paths[:0]
+			if options.Enumerate != nil {
+				for _, params := range options.Enumerate(route) {
+					p, err := fill(route.Pattern, params)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("%w: from %s", err, route.File))
+						continue
+					}
+					paths = append(paths, p)
+				}
+			}
+		}
+
+		for _, p := range paths {
+			errs = append(errs, exportPage(mux, dir, p, options.Host))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// exportPage catches a page and writes it to an `index.html` in a dir
+func exportPage(mux http.Handler, dir, target, host string) error {
+	name, err := exportName(dir, target)
+	if err != nil {
+		return err
+	}
+	name = filepath.Join(name, "index.html")
+
+	b, err := exportCatch(mux, target, host)
+	if err != nil {
+		return err
+	}
+
+	// a static site has nothing to reload
+	page := bytes.Replace(b, []byte(reloadScript), nil, 1)
+	return writeExport(name, page)
+}
+
+// exportCatch catches a fish with GET for export, giving what it wrote
+func exportCatch(mux http.Handler, target, host string) (b []byte, err error) {
+	r, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.Host = host
+	r.RequestURI = target
+
+	// a streamed tuna that snags after its prelude aborts, which only
+	// a server would recover, so it is not ok instead of a panic
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		if p != http.ErrAbortHandler {
+			panic(p)
+		}
+		b, err = nil, fmt.Errorf("%w: %s was cut short", ErrExportStatus, target)
+	}()
+
+	w := &exportRecorder{header: http.Header{}}
+	mux.ServeHTTP(w, r)
+	if w.status != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s gave %d", ErrExportStatus, target, w.status)
+	}
+	return w.body.Bytes(), nil
+}
+
+// exportName gives where a url path is written within a dir
+func exportName(dir, target string) (string, error) {
+	unescaped, err := url.PathUnescape(target)
+	if err != nil {
+		return "", err
+	}
+	if path.Clean(unescaped) != unescaped {
+		return "", fmt.Errorf("%w: %s", ErrExportPath, target)
+	}
+	return filepath.Join(dir, filepath.FromSlash(unescaped)), nil
+}
+
+// exportFile writes a file at a url path within a dir
+func exportFile(dir, target string, b []byte) error {
+	name, err := exportName(dir, target)
+	if err != nil {
+		return err
+	}
+	return writeExport(name, b)
+}
+
+// writeExport writes a file, making its dir if needed
+func writeExport(name string, b []byte) error {
+	err := os.MkdirAll(filepath.Dir(name), 0o755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, b, 0o644)
+}
+
+// exportBreed catches a fish of a breed and writes what its breed gave
+// at its pattern, and at its fingerprint if it has one
+func exportBreed[K any](mux http.Handler, dir string, f *Fish[K], host string) error {
+	b, err := exportCatch(mux, f.pattern, host)
+	if err != nil {
+		return err
+	}
+	errs := []error{exportFile(dir, f.pattern, b)}
+	if fingerprinted(f) {
+		errs = append(errs, exportFile(dir, fingerprint(f.pattern, f.hash), b))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,130 @@
+package aquatic
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestExport(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/ux.html":           {Data: []byte(`<p>home</p>`)},
+		"ux/_layout.html":      {Data: []byte(`<html><head>{{ .Bobber }}</head><body>{{ .Body }}</body></html>`)},
+		"ux/style.css":         {Data: []byte(`p { color: red; }`)},
+		"ux/smile.png":         {Data: []byte("\x89PNG\r\n\x1a\n")},
+		"ux/user/user.html":    {Data: []byte(`<p>users</p>`)},
+		"ux/user.id.edit.html": {Data: []byte(`<p>edit</p>`)},
+		"ux/team.id.html":      {Data: []byte(`<p>team</p>`)},
+		"ux/user/_card.html":   {Data: []byte(`<p>card</p>`)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{HotReloadBrowser: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	err = Export(&pond, dir, ExportOptions{
+		Enumerate: func(route Route) []map[string]string {
+			if route.Pattern != "/user/{id}/edit" {
+				return nil
+			}
+			return []map[string]string{{"id": "3"}, {"id": "a b"}}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) string {
+		b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err, name)
+		}
+		return string(b)
+	}
+
+	hash := hashOf(fsys["ux/style.css"].Data)
+	home := read("index.html")
+	if !strings.Contains(home, `<p>home</p>`) ||
+		!strings.Contains(home, `href="/style.`+hash+`.css"`) ||
+		strings.Contains(home, "?v=") ||
+		strings.Contains(home, "EventSource") {
+		t.Fatal(errUnexpectedValue, home)
+	}
+	if read("style."+hash+".css") != `p { color: red; }` {
+		t.Fatal(errUnexpectedValue, "style")
+	}
+
+	pngHash := hashOf(fsys["ux/smile.png"].Data)
+	read("smile.png")
+	read("smile." + pngHash + ".png")
+
+	read("user/index.html")
+	if !strings.Contains(read("user/3/edit/index.html"), "edit") {
+		t.Fatal(errUnexpectedValue, "user 3")
+	}
+	read("user/a b/edit/index.html")
+
+	// without path values, not exported
+	for _, name := range []string{"team", "user/_card", "_layout"} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Fatal(errUnexpectedValue, name)
+		}
+	}
+}
+
+func TestExport_Outside(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/user.id.html": {Data: []byte(`<p>user</p>`)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	err = Export(&pond, filepath.Join(dir, "site"), ExportOptions{
+		Enumerate: func(_ Route) []map[string]string {
+			return []map[string]string{{"id": ".."}}
+		},
+	})
+	if !errors.Is(err, ErrExportPath) {
+		t.Fatal(errUnexpectedValue, err)
+	}
+}
+
+func TestExport_StreamHead(t *testing.T) {
+	pond := mockStreamPond(t, NewPondOptions{StreamHead: true}, mockStreamLayout)
+
+	dir := t.TempDir()
+	err := Export(pond, dir, ExportOptions{})
+	if !errors.Is(err, ErrExportStatus) {
+		t.Fatal(errUnexpectedValue, err)
+	}
+	_, err = os.Stat(filepath.Join(dir, "broken", "index.html"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal(errUnexpectedValue, err)
+	}
+	_, err = os.Stat(filepath.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	tests := map[string]string{
+		"/style/simple.css": "/style/simple.abc.css",
+		"/a.b/c.min.js":     "/a.b/c.min.abc.js",
+		"/file":             "/file.abc",
+	}
+	for pattern, expected := range tests {
+		if s := fingerprint(pattern, "abc"); s != expected {
+			t.Fatal(errUnexpectedValue, pattern, s)
+		}
+	}
+}
@@ -6,14 +6,21 @@ package aquatic
 import (
 	"crypto/md5"
 	"fmt"
-	"io"
+	"html/template"
+	"io/fs"
+	"log/slog"
 	"maps"
 	"mime"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
-	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/Isaac799/go-fish/pkg/bridge"
 )
 
 const (
@@ -39,13 +46,47 @@ const (
 	// Similar to a global sardine usage but instead of reading file
 	// it uses pre-defined bytes.
 	FishKindMackerel
+	// FishKindWhaleShark is the biggest fish. It swallows a tuna whole,
+	// being the html document around it. Given the bobber and executed tuna.
+	// Identified by mime [ text/html ] & name [ _layout ].
+	// Nearest dir wins, then global, then the mackerel layout. Not served.
+	FishKindWhaleShark
+	// FishKindPuffer puffs up when something goes wrong. Served as a page
+	// in place of a fish that could not be caught. Given `.Snag` as bait.
+	// Identified by mime [ text/html ] & name of a status [ _404 | _500 ].
+	// Nearest dir wins, then global. Not served on its own.
+	FishKindPuffer
+	// FishKindBreed is a kind of fish given to a pond, see [Breed].
+	// Identified by the classifier of its breed, before any other kind.
+	// Cached as its breed says.
+	FishKindBreed
+)
+
+const (
+	// layoutTemplateName is the name a whale shark is found by
+	layoutTemplateName = "_layout"
+	// headTemplateName is the name of the block a tuna
+	// can define to add to the head of the document
+	headTemplateName = "head"
 )
 
+// emptyHead is the head of a tuna that did not define one
+var emptyHead = []byte(`{{define "` + headTemplateName + `"}}{{end}}`)
+
+// hasHead is true for a fish swallowed by a whale shark with a head
+// block apart from its body. A tuna named `head` is the block, so has none.
+func hasHead[K any](f *Fish[K]) bool {
+	return swallowed(f.kind) && f.templateName != headTemplateName
+}
+
 const (
 	// browserCacheDurationSeconds is used to cache documents
 	// such as .css. To help prevent invalid cache we replace
 	// the names with a hash of their content
 	browserCacheDurationSeconds = 86400 // 1 day
+	// immutableCacheControl is for a fish caught by its fingerprint,
+	// which never changes since its name would
+	immutableCacheControl = "public, max-age=31536000, immutable"
 )
 
 // mackerelHTMLElement provides a system fish for
@@ -53,7 +94,9 @@ const (
 func mackerelHTMLElement[K any]() Fish[K] {
 	// A template element that works with bridge.HTMLelement.
 	// Whitespace sensitive because text area value is inner text.
-	elementTemplate := []byte(`{{define "_element"}}{{if .Tag}}{{if .SelfClosing}}<{{.Tag}}{{range $key, $value := .Attributes}} {{$key}}="{{$value}}" {{end}} />{{if .Children}}{{range $key, $value := .Children}}{{template "_element" $value}}{{end}}{{end}}{{else}}<{{.Tag}} {{range $key, $value := .Attributes}} {{$key}}="{{$value}}" {{end}}>{{.InnerText}}{{range $key, $value := .Children}} {{template "_element" $value}}{{end}}</{{.Tag}}>{{end}}{{end}}{{end}}`)
+	// Opening and closing tags come from mackerel tackle since html/template
+	// will not let an action decide a tag name.
+	elementTemplate := []byte(`{{define "_element"}}{{if .Tag}}{{_elementOpen .}}{{if .SelfClosing}}{{range $key, $value := .Children}}{{template "_element" $value}}{{end}}{{else}}{{.InnerText}}{{range $key, $value := .Children}} {{template "_element" $value}}{{end}}{{_elementClose .}}{{end}}{{end}}{{end}}`)
 	randomStr := "3b5d5c3712955042212316173ccf37be"
 	mackerel := Fish[K]{
 		kind:      FishKindMackerel,
@@ -70,6 +113,139 @@ func mackerelHTMLElement[K any]() Fish[K] {
 	return mackerel
 }
 
+// mackerelLayout provides a system fish for the layout used
+// when a pond has no whale shark of its own
+func mackerelLayout[K any]() Fish[K] {
+	layoutTemplate := []byte(`{{define "` + layoutTemplateName + `"}}<!DOCTYPE html><html lang="en"><head><meta charset="UTF-8"><meta name="viewport" content="width=device-width, initial-scale=1.0" >{{.Bobber}}{{.Head}}</head><body>{{.Body}}</body></html>{{end}}`)
+	mackerel := Fish[K]{
+		kind:         FishKindMackerel,
+		mime:         "text/html",
+		coral:        layoutTemplate,
+		templateName: layoutTemplateName,
+		filePath:     "a34b6e9d0c5a4c1c8f3e2b7d9a6f1e04",
+	}
+	return mackerel
+}
+
+// elementName is what a tag or attribute name must look like
+// to be written by the mackerel
+var elementName = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_:.-]*$`)
+
+// elementURLAttributes hold a url, so their values are filtered
+// the same way html/template filters a url
+var elementURLAttributes = map[string]bool{
+	"action":     true,
+	"formaction": true,
+	"href":       true,
+	"src":        true,
+}
+
+// mackerelTackle is given to every template so the mackerel can be caught.
+// Values are escaped here since the template escaper cannot see inside
+// a tag it did not write.
+var mackerelTackle = template.FuncMap{
+	"_elementOpen": func(el bridge.HTMLElement) template.HTML {
+		if !elementName.MatchString(el.Tag) {
+			return ""
+		}
+
+		b := strings.Builder{}
+		b.WriteString("<")
+		b.WriteString(el.Tag)
+
+		keys := make([]string, 0, len(el.Attributes))
+		for k := range el.Attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if !elementName.MatchString(k) {
+				continue
+			}
+			b.WriteString(" ")
+			b.WriteString(k)
+			b.WriteString(`="`)
+			b.WriteString(template.HTMLEscapeString(elementAttribute(k, el.Attributes[k])))
+			b.WriteString(`"`)
+		}
+
+		if el.SelfClosing {
+			b.WriteString(" />")
+		} else {
+			b.WriteString(">")
+		}
+		return template.HTML(b.String())
+	},
+	"_elementClose": func(el bridge.HTMLElement) template.HTML {
+		if !elementName.MatchString(el.Tag) {
+			return ""
+		}
+		return template.HTML("</" + el.Tag + ">")
+	},
+}
+
+// elementAttribute gives the value an attribute of a mackerel element is
+// written with, filtered as html/template would the same attribute. An
+// event handler holds script, so it is never written as given.
+func elementAttribute(k, v string) string {
+	name := strings.TrimPrefix(strings.ToLower(k), "data-")
+	if _, local, found := strings.Cut(name, ":"); found {
+		name = local
+	}
+	switch {
+	case strings.HasPrefix(name, "on"):
+		return "ZgotmplZ"
+	case name == "style" && !safeStyle(v):
+		return "ZgotmplZ"
+	case elementURLAttributes[name] && !safeURL(v):
+		return "#ZgotmplZ"
+	}
+	return v
+}
+
+// stylePropertyName is what a property in a style attribute must look like
+var stylePropertyName = regexp.MustCompile(`^-?[a-zA-Z][a-zA-Z0-9-]*$`)
+
+// safeStyle is true if every declaration in a style attribute has a plain
+// property and a value html/template would let through, so nothing like
+// `url(javascript:...)` or `expression(...)` is written
+func safeStyle(s string) bool {
+	for _, decl := range strings.Split(s, ";") {
+		if len(strings.TrimSpace(decl)) == 0 {
+			continue
+		}
+		k, v, found := strings.Cut(decl, ":")
+		if !found || !stylePropertyName.MatchString(strings.TrimSpace(k)) {
+			return false
+		}
+		if strings.ContainsAny(v, "\x00\"'()/@[\\]`{}<>") || strings.Contains(v, "--") {
+			return false
+		}
+		plain := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, v)
+		if strings.Contains(plain, "expression") || strings.Contains(plain, "mozbinding") {
+			return false
+		}
+	}
+	return true
+}
+
+// safeURL is true if a url has no scheme or a scheme I trust
+func safeURL(s string) bool {
+	s = strings.TrimSpace(s)
+	i := strings.IndexAny(s, ":/?#")
+	if i == -1 || s[i] != ':' {
+		return true
+	}
+	scheme := strings.ToLower(s[:i])
+	return scheme == "http" || scheme == "https" || scheme == "mailto"
+}
+
 // License is a requirement to catch a fish.
 // acts as a middleware. Return true if license is passed
 type License func(next http.Handler) http.Handler
@@ -77,12 +253,54 @@ type License func(next http.Handler) http.Handler
 type masterBait[T, K any] struct {
 	Local  K
 	Global T
+	// Snag is only given to a puffer fish
+	Snag *Snag
+}
+
+// wholeTuna is given to a whale shark as it swallows a tuna
+type wholeTuna[T, K any] struct {
+	// Bobber is the links for the head of the document
+	Bobber template.HTML
+	// Head is the executed head block of the tuna, if it defined one
+	Head template.HTML
+	// Body is the executed tuna
+	Body   template.HTML
+	Local  K
+	Global T
+	// Snag is only given when swallowing a puffer fish
+	Snag *Snag
 }
 
 // Bait is to be gobbled up by a fish before catching it.
 // A func that has access to the request and returns template data
 type Bait[T any] func(r *http.Request) T
 
+// Lure is bait that can fail. It is given the response too, so it can
+// set headers or cookies before the template is executed. An error
+// snags the catch, see [ErrNotFound] and [Redirect] for the status given.
+type Lure[T any] func(w http.ResponseWriter, r *http.Request) (T, error)
+
+// Action runs for a fish caught with anything but GET or HEAD, after
+// licenses and before bait. Such as validating and saving a form. The
+// haul decides what is rendered after. An error snags the same as a lure.
+type Action[K any] func(w http.ResponseWriter, r *http.Request) (Haul[K], error)
+
+// Haul is what an action caught, deciding what is rendered after it
+type Haul[K any] struct {
+	// Local is given to the fish rendered in place of its bait,
+	// such as a form with its errors. Bait is used if zero.
+	Local K
+	// Fish is the template name of the fish rendered, such as a
+	// sardine in the same school. The fish caught if empty.
+	Fish string
+	// Redirect sends the request elsewhere instead of rendering.
+	// For htmx requests it is given as `HX-Redirect`.
+	Redirect string
+	// Status is given with the render or redirect. 200 for a
+	// render or 303 for a redirect if zero.
+	Status int
+}
+
 // Fish is an item found form the template dir.
 type Fish[K any] struct {
 	kind           int
@@ -94,12 +312,27 @@ type Fish[K any] struct {
 	scopedFilePath string
 	filePath       string
 
+	// fsys is the file system of the pond the fish was found in,
+	// so a fish that flows into another pond is still read from home
+	fsys fs.FS
+	// fsPath is where the fish is in its file system
+	fsPath string
+	// modTime is when the fish last changed. Zero if its
+	// file system does not know, such as an embedded one.
+	modTime time.Time
+	// paramTypes are the types path values were given in the file
+	// name, such as int for `user.id@int.html`. Checked before
+	// licenses and bait.
+	paramTypes map[string]string
+	// breed is what a fish of kind breed is caught with
+	breed *Breed
+
 	// fish found in same dir
 	school []Fish[K]
 
 	// coral is bytes of template since it ony needs to be read once.
-	// FishKindMackerel have it pre-defined. Other fish its populated on first
-	// time parsing
+	// FishKindMackerel have it pre-defined. Tuna and sardine have it
+	// grown when found, a tuna keeping it bare. Other fish have none.
 	coral []byte
 
 	// reef is combination of all coral (templates bytes) combined into
@@ -112,6 +345,20 @@ type Fish[K any] struct {
 	// Only relevant for tuna. Saved for reuse after first determined.
 	bobber []byte
 
+	// prelude is the start of the document of a tuna up to and
+	// including its bobber, if it needs no bait. Flushed before
+	// bait is caught when the pond streams heads.
+	prelude []byte
+
+	// hooked is the reef parsed into a template. Parsed once when lines
+	// are cast and executed for every catch after. Templates are safe to
+	// execute concurrently so it is never cloned.
+	hooked caught
+
+	// puffers are the nearest puffer fish for each status.
+	// Found while lines are cast.
+	puffers map[int]*Fish[K]
+
 	// Licenses is a collection of licenses a user must have
 	// to catch a fish. Checked after pond licenses, in the
 	// order added. To catch a fish all pond and fish licenses
@@ -122,6 +369,19 @@ type Fish[K any] struct {
 	// executed template, or eaten by the fish before caught
 	Bait Bait[K]
 
+	// Lure is used in place of bait if provided. Its
+	// error decides the puffer fish given instead.
+	Lure Lure[K]
+
+	// Action runs before rendering for requests that are not
+	// just looking (GET and HEAD). Not gobbled by the school.
+	Action Action[K]
+
+	// Methods a fish can be caught with, such as POST. Any method
+	// if none. Given by a file name suffix like `_row+post.html` or
+	// by stock. Others at the same pattern are given 405.
+	Methods []string
+
 	// Tackle helps catch a fish.
 	// Given to a template to help transform the data.
 	Tackle template.FuncMap
@@ -132,11 +392,21 @@ func Patten[K any](f *Fish[K]) string {
 	return f.pattern
 }
 
-// Gobble has one fish gobble up another. Gaining its Licenses, Tackle, and Bait (if not already has some).
+// Gobble has one fish gobble up another. Gaining its Licenses, Tackle, Bait, Lure, Action and Methods (if not already has some).
+// Action and Methods are not gobbled by the school, since they are caught at their own pattern.
 func Gobble[T any](f *Fish[T], f2 *Fish[T]) {
-	if f.Bait == nil && f2.Bait != nil {
+	if f.Action == nil && f2.Action != nil {
+		f.Action = f2.Action
+	}
+	if f.Methods == nil && f2.Methods != nil {
+		f.Methods = normalMethods(f2.Methods)
+	}
+	if f.Bait == nil && f2.Bait != nil && baited(f.kind) {
 		f.Bait = f2.Bait
 	}
+	if f.Lure == nil && f2.Lure != nil && baited(f.kind) {
+		f.Lure = f2.Lure
+	}
 	if f.Licenses == nil {
 		f.Licenses = make([]License, 0, len(f2.Licenses))
 	}
@@ -148,9 +418,15 @@ func Gobble[T any](f *Fish[T], f2 *Fish[T]) {
 	}
 	maps.Copy(f.Tackle, f2.Tackle)
 	for i := range f.school {
+		if !baited(f.school[i].kind) {
+			continue
+		}
 		if f.school[i].Bait == nil && f2.Bait != nil {
 			f.school[i].Bait = f2.Bait
 		}
+		if f.school[i].Lure == nil && f2.Lure != nil {
+			f.school[i].Lure = f2.Lure
+		}
 		if f.school[i].kind != FishKindSardine {
 			continue
 		}
@@ -167,33 +443,34 @@ func Gobble[T any](f *Fish[T], f2 *Fish[T]) {
 	}
 }
 
+// baited is true for kinds of fish that gobble bait and lures. A puffer
+// fish must not run the lure that just snagged, and a whale shark or
+// mackerel is given the bait of what it swallows.
+func baited(kind int) bool {
+	return kind == FishKindTuna || kind == FishKindSardine
+}
+
 // Kind reads back the kind of a fish
 func Kind[T, K any](f *Fish[K]) int {
 	return f.kind
 }
 
-func newFish[T, K any](entry os.DirEntry, pathBase string, pond *Pond[T, K]) (*Fish[K], error) {
-	pathBase = filepath.ToSlash(pathBase)
-
+func newFish[T, K any](entry fs.DirEntry, pathBase string, pond *Pond[T, K]) (*Fish[K], error) {
 	info, err := entry.Info()
 	if err != nil {
 		return nil, err
 	}
-	ext := filepath.Ext(info.Name())
+	ext := path.Ext(info.Name())
 
 	// since I want to cache styling while preventing
 	// an invalid cache we make the name based on a hash
 	// of its content
-	file, err := os.Open(filepath.Join(pathBase, entry.Name()))
+	fsPath := path.Join(pathBase, info.Name())
+	b, err := fs.ReadFile(pond.fsys, fsPath)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-	b, err := io.ReadAll(file)
-	if err != nil {
-		return nil, err
-	}
-	hash := fmt.Sprintf("%x", md5.Sum(b))
+	hash := hashOf(b)
 
 	// so windows mime types suck and using mime package not always work
 	// e.g. windows not knowing what a woff2 file was and causing
@@ -206,13 +483,20 @@ func newFish[T, K any](entry os.DirEntry, pathBase string, pond *Pond[T, K]) (*F
 	}
 
 	if len(mime) == 0 {
-		fmt.Println("gofish warn: cannot determine mime type of: ", ext)
+		pond.options.Logger.Warn("cannot determine mime type", slog.String("ext", ext), slog.String("file", info.Name()))
 		return nil, ErrInvalidExtension
 	}
 
 	kind := -1
-	if strings.HasPrefix(mime, "text/html") {
-		if strings.HasPrefix(info.Name(), "_") {
+	breed := breedOf(pond, mime, ext)
+	if breed != nil {
+		kind = FishKindBreed
+	} else if strings.HasPrefix(mime, "text/html") {
+		if _, isStatus := pufferStatus(strings.TrimSuffix(info.Name(), ext)); isStatus {
+			kind = FishKindPuffer
+		} else if strings.TrimSuffix(info.Name(), ext) == layoutTemplateName {
+			kind = FishKindWhaleShark
+		} else if strings.HasPrefix(info.Name(), "_") {
 			kind = FishKindSardine
 		} else {
 			kind = FishKindTuna
@@ -232,43 +516,73 @@ func newFish[T, K any](entry os.DirEntry, pathBase string, pond *Pond[T, K]) (*F
 	}
 
 	name := info.Name()
-	if kind == FishKindTuna || kind == FishKindSardine {
+	if kind == FishKindTuna || kind == FishKindSardine || kind == FishKindWhaleShark || kind == FishKindPuffer {
 		name = strings.TrimSuffix(info.Name(), ext)
 	}
 	templateName := name
 
-	filePath := filepath.Join(pathBase, info.Name())
-	filePath = filepath.ToSlash(filePath)
+	// a method suffix is only part of the template name, so
+	// `_row+post.html` is caught with `POST /_row`. Not after a
+	// `.`, where `blog.post.html` is a path value `/blog/{post}`.
+	methods := []string(nil)
+	if kind == FishKindTuna || kind == FishKindSardine {
+		if cut, method, found := cutMethod(name); found {
+			name = cut
+			methods = []string{method}
+		}
+	}
 
-	scopedFilePath := strings.Replace(filePath, pond.templateDir, "", 1)
-	scopedFilePath = filepath.ToSlash(scopedFilePath)
+	// a name ending in `$` is only caught at its exact pattern,
+	// and one ending in a `...` value catches the rest of a path
+	exact, wildcard := false, false
+	if kind == FishKindTuna || kind == FishKindSardine {
+		name, exact = strings.CutSuffix(name, "$")
+		name, wildcard = strings.CutSuffix(name, "...")
+	}
 
-	pattern := filepath.Join(pathBase, name)
-	pattern = filepath.ToSlash(pattern)
+	filePath := path.Join(pond.origin, fsPath)
 
-	pattern = strings.Replace(pattern, pond.templateDir, "", 1)
+	scopedFilePath := scope(pond, fsPath)
+
+	pattern := scope(pond, path.Join(pathBase, name))
 	pattern = strings.ReplaceAll(pattern, " ", "-")
 
 	pattern = strings.ToLower(pattern)
 
 	isLanding := false
 	if kind == FishKindTuna {
-		fileParts := strings.Split(pathBase, "/")
-		if len(fileParts) > 0 {
-			parentDir := fileParts[len(fileParts)-1]
-			isLanding = parentDir == name
+		parentDir := path.Base(pathBase)
+		if pathBase == pond.templateDir {
+			parentDir = pond.rootName
 		}
+		isLanding = parentDir == name
 	}
 
+	var paramTypes map[string]string
 	if kind == FishKindTuna || kind == FishKindSardine {
 		patternParts := strings.Split(pattern, ".")
 		newPatternParts := []string{}
 		for i, e := range patternParts {
 			if (i+1)%2 == 0 {
-				param := "{" + e + "}"
+				// a type is only checked, the mux is not given it
+				name, typ := cutParamType(e)
+				if len(typ) > 0 {
+					if paramTypes == nil {
+						paramTypes = map[string]string{}
+					}
+					paramTypes[name] = typ
+				}
+				param := "{" + name + "}"
+				if wildcard && i == len(patternParts)-1 {
+					param = "{" + name + "...}"
+				}
 				newPatternParts = append(newPatternParts, param)
 				continue
 			}
+			if wildcard && i == len(patternParts)-1 {
+				// only a value can be a wildcard
+				e += "..."
+			}
 			newPatternParts = append(newPatternParts, e)
 		}
 		pattern = strings.Join(newPatternParts, "/")
@@ -279,14 +593,27 @@ func newFish[T, K any](entry os.DirEntry, pathBase string, pond *Pond[T, K]) (*F
 
 	if kind == FishKindTuna {
 		if isLanding {
-			pattern = strings.TrimSuffix(pattern, templateName)
+			pattern = strings.TrimSuffix(pattern, name)
 		}
 		if pattern != "/" && strings.HasSuffix(pattern, "/") {
 			pattern = strings.TrimSuffix(pattern, "/")
 		}
 	}
+	if exact {
+		pattern = strings.TrimSuffix(pattern, "/") + "/{$}"
+	}
+
+	// a tuna coral is left bare since it is only ever the body of its
+	// own reef. That way it can define blocks, like its head.
+	var c []byte
+	if kind == FishKindTuna || kind == FishKindPuffer {
+		c = b
+	} else if kind == FishKindSardine || kind == FishKindWhaleShark {
+		c = coral(templateName, b)
+	}
 
 	f := Fish[K]{
+		coral:          c,
 		kind:           kind,
 		mime:           mime,
 		hash:           hash,
@@ -295,53 +622,94 @@ func newFish[T, K any](entry os.DirEntry, pathBase string, pond *Pond[T, K]) (*F
 		templateName:   templateName,
 		filePath:       filePath,
 		scopedFilePath: scopedFilePath,
+		fsys:           pond.fsys,
+		fsPath:         fsPath,
+		modTime:        info.ModTime(),
+		paramTypes:     paramTypes,
+		breed:          breed,
 		Licenses:       []License{},
+		Methods:        methods,
 	}
 
 	return &f, nil
 }
 
-// coral will wrap a file content in the define syntax.
-// Enforcing template name scheme and reducing template lines n - 2.
-// Once coral is discovered for the first time it is saved in the fish for re use.
-func coral[K any](f *Fish[K]) ([]byte, error) {
-	if f.coral != nil {
-		return f.coral, nil
-	}
+// fileMethods are the methods a fish can be named for
+var fileMethods = map[string]string{
+	"get":    http.MethodGet,
+	"post":   http.MethodPost,
+	"put":    http.MethodPut,
+	"patch":  http.MethodPatch,
+	"delete": http.MethodDelete,
+}
 
-	file, err := os.Open(f.filePath)
-	if err != nil {
-		return nil, err
+// cutMethod gives back a name without its method suffix, and the
+// method it was for. Such as `_row` and POST for `_row+post`.
+func cutMethod(name string) (string, string, bool) {
+	i := strings.LastIndex(name, "+")
+	if i == -1 {
+		return name, "", false
 	}
-	defer file.Close()
-	info, err := file.Stat()
-	if err != nil {
-		return nil, err
+	method, exists := fileMethods[strings.ToLower(name[i+1:])]
+	if !exists {
+		return name, "", false
 	}
+	return name[:i], method, true
+}
 
-	prefix := fmt.Appendf(nil, "{{define \"%s\"}}", f.templateName)
-	suffix := []byte("{{end}}")
+// normalMethods gives methods upper case without repeats, sorted
+func normalMethods(methods []string) []string {
+	normal := make([]string, 0, len(methods))
+	for _, m := range methods {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		if len(m) == 0 || slices.Contains(normal, m) {
+			continue
+		}
+		normal = append(normal, m)
+	}
+	slices.Sort(normal)
+	return normal
+}
 
-	size := len(prefix) + int(info.Size()) + len(suffix)
-	buffer := make([]byte, size)
+// swallowed is true for fish served as a page, so they
+// are swallowed by a whale shark
+func swallowed(kind int) bool {
+	return kind == FishKindTuna || kind == FishKindPuffer
+}
 
-	copy(buffer, prefix)
-	n, err := file.Read(buffer[len(prefix) : len(prefix)+int(info.Size())])
-	if err != nil {
-		return nil, err
-	}
+// hashOf gives the hash a fish is known by for its content
+func hashOf(b []byte) string {
+	return fmt.Sprintf("%x", md5.Sum(b))
+}
 
-	copy(buffer[len(prefix)+n:], suffix)
-	f.coral = buffer
+// fingerprint gives a path with a hash in its name, so it
+// changes when the content does. Such as `/style/simple.<hash>.css`.
+func fingerprint(pattern, hash string) string {
+	ext := path.Ext(pattern)
+	return strings.TrimSuffix(pattern, ext) + "." + hash + ext
+}
 
-	return buffer, nil
+// coral will wrap a file content in the define syntax.
+// Enforcing template name scheme and reducing template lines n - 2.
+// Coral is grown once when a fish is found, so it is only ever read after.
+func coral(templateName string, b []byte) []byte {
+	prefix := fmt.Appendf(nil, "{{define \"%s\"}}", templateName)
+	suffix := []byte("{{end}}")
+
+	buffer := make([]byte, len(prefix)+len(b)+len(suffix))
+	n := copy(buffer, prefix)
+	n += copy(buffer[n:], b)
+	copy(buffer[n:], suffix)
+
+	return buffer
 }
 
 // reef combines the coral of dependent fish and itself.
 // Once a reef is discovered for the first time it is saved in the fish for re use.
-func reef[T, K any](f *Fish[K], pond *Pond[T, K]) ([]byte, error) {
+// Only discovered while lines are cast, so never written while a fish is caught.
+func reef[T, K any](f *Fish[K], pond *Pond[T, K]) []byte {
 	if f.reef != nil {
-		return f.reef, nil
+		return f.reef
 	}
 
 	// a map to store the various fish needed to be eaten
@@ -359,29 +727,22 @@ func reef[T, K any](f *Fish[K], pond *Pond[T, K]) ([]byte, error) {
 		if _, exists := eaten[e.templateName]; exists {
 			continue
 		}
-		b, err := coral(e)
-		if err != nil {
-			return nil, err
-		}
-		size += len(b)
-		eaten[e.templateName] = b
+		size += len(e.coral)
+		eaten[e.templateName] = e.coral
 	}
 
 	// local sardines first to give the consumer (tuna or sardine)
-	// access to its local dependent templates
+	// access to its local dependent templates. Whale sharks are
+	// scoped the same, so nearest dir wins.
 	for _, e := range f.school {
-		if e.kind != FishKindSardine {
+		if e.kind != FishKindSardine && e.kind != FishKindWhaleShark {
 			continue
 		}
 		if _, exists := eaten[e.templateName]; exists {
 			continue
 		}
-		b, err := coral(&e)
-		if err != nil {
-			return nil, err
-		}
-		size += len(b)
-		eaten[e.templateName] = b
+		size += len(e.coral)
+		eaten[e.templateName] = e.coral
 	}
 
 	// global sardines come after local ones so they do not
@@ -389,29 +750,38 @@ func reef[T, K any](f *Fish[K], pond *Pond[T, K]) ([]byte, error) {
 	// _nav in this fish dir we already consumed the local
 	// one, and it cannot be re defined
 	for _, e := range pond.shad {
-		if e.kind != FishKindSardine {
+		if e.kind != FishKindSardine && e.kind != FishKindWhaleShark {
 			continue
 		}
 		if _, exists := eaten[e.templateName]; exists {
 			continue
 		}
-		b, err := coral(e)
-		if err != nil {
-			return nil, err
-		}
-		size += len(b)
-		eaten[e.templateName] = b
+		size += len(e.coral)
+		eaten[e.templateName] = e.coral
+	}
+
+	// a tuna must be swallowed by something, so if no whale
+	// shark was found the mackerel layout is used
+	if _, exists := eaten[layoutTemplateName]; !exists && swallowed(f.kind) {
+		layout := mackerelLayout[K]()
+		size += len(layout.coral)
+		eaten[layoutTemplateName] = layout.coral
+	}
+
+	// a tuna may define its own head, but if it does not
+	// an empty one is there for the whale shark
+	if hasHead(f) {
+		size += len(emptyHead)
+		eaten[headTemplateName] = emptyHead
 	}
 
 	// finally we can consume the 'main' fish (tuna or sardine)
-	// this is to ensure not re define if is sardine
+	// this is to ensure not re define if is sardine. It is kept
+	// out of the map since a bare tuna coral must come last
+	var main []byte
 	if _, exists := eaten[f.templateName]; !exists {
-		b, err := coral(f)
-		if err != nil {
-			return nil, err
-		}
-		size += len(b)
-		eaten[f.templateName] = b
+		size += len(f.coral)
+		main = f.coral
 	}
 
 	// now the cool part, a sliding copy into a single pre
@@ -422,7 +792,8 @@ func reef[T, K any](f *Fish[K], pond *Pond[T, K]) ([]byte, error) {
 		n := copy(buff[last:last+len(v)], v)
 		last += n
 	}
+	copy(buff[last:], main)
 
 	f.reef = buff
-	return buff, nil
+	return buff
 }
@@ -2,117 +2,223 @@ package aquatic
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"html/template"
 	"io"
+	"io/fs"
+	"log/slog"
 	"net/http"
-	"os"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
-	"text/template"
+	"sync"
+	texttemplate "text/template"
 )
 
+// caught is a parsed reef ready to be executed. Satisfied
+// by both html and text templates.
+type caught interface {
+	ExecuteTemplate(wr io.Writer, name string, data any) error
+}
+
+// parseReef parses a reef with contextual auto-escaping. Unless the pond
+// opted out with RawOutput, then it is parsed as a text template.
+func parseReef[T, K any](f *Fish[K], pond *Pond[T, K], reef []byte) (caught, error) {
+	if pond.options.RawOutput {
+		t := texttemplate.New(f.templateName).Funcs(texttemplate.FuncMap(mackerelTackle))
+		t.Funcs(texttemplate.FuncMap{"url": urlTackle(pond), "asset": assetTackle(pond)})
+		if f.Tackle != nil {
+			t.Funcs(texttemplate.FuncMap(f.Tackle))
+		}
+		return t.Parse(string(reef))
+	}
+
+	t := template.New(f.templateName).Funcs(mackerelTackle)
+	t.Funcs(template.FuncMap{"url": urlTackle(pond), "asset": assetTackle(pond)})
+	if f.Tackle != nil {
+		t.Funcs(f.Tackle)
+	}
+	return t.Parse(string(reef))
+}
+
+// hook parses the reef of a fish into a template.
+// Once a fish is hooked it is saved in the fish for re use.
+// Only hooked while lines are cast, so never written while a fish is caught.
+func hook[T, K any](f *Fish[K], pond *Pond[T, K]) (caught, error) {
+	if f.hooked != nil {
+		return f.hooked, nil
+	}
+
+	parsed, err := parseReef(f, pond, reef(f, pond))
+	if err != nil {
+		return nil, err
+	}
+
+	f.hooked = parsed
+	return parsed, nil
+}
+
 func handlerSardine[T, K any](f *Fish[K], pond *Pond[T, K]) http.HandlerFunc {
+	parsed, hookErr := hook(f, pond)
+
 	return func(w http.ResponseWriter, r *http.Request) {
-		t := template.New(f.templateName)
+		r, cancel := timed(r, pond)
+		defer cancel()
 
-		buff, err := reef(f, pond)
-		if err != nil {
-			fmt.Print(err)
-			w.WriteHeader(http.StatusInternalServerError)
+		if hookErr != nil {
+			Snagged(w, r, http.StatusInternalServerError, hookErr)
 			return
 		}
 
-		if f.Tackle != nil {
-			t.Funcs(f.Tackle)
-		}
-
-		parsed, err := t.Parse(string(buff))
+		haul, err := act(w, r, f)
 		if err != nil {
-			fmt.Print(err)
-			w.WriteHeader(http.StatusInternalServerError)
+			baitSnagged(w, r, err)
 			return
 		}
-
-		var globalBait T
-		var localBait K
-
-		if pond.Chum != nil {
-			globalBait = pond.Chum(r)
-		}
-		if f.Bait != nil {
-			localBait = f.Bait(r)
+		if len(haul.Redirect) > 0 {
+			redirect(w, r, haul)
+			return
 		}
 
-		pageData := masterBait[T, K]{
-			Local:  localBait,
-			Global: globalBait,
+		pageData, err := catchBait(w, r, f, pond, haul)
+		if err != nil {
+			baitSnagged(w, r, err)
+			return
 		}
 
 		// want to exe template into this to get len for res
 		resBytes := []byte{}
 		resBuff := bytes.NewBuffer(resBytes)
 
-		err = parsed.ExecuteTemplate(resBuff, f.templateName, pageData)
+		err = parsed.ExecuteTemplate(resBuff, haulName(f, haul), pageData)
 		if err != nil {
-			fmt.Print(err)
-			w.WriteHeader(http.StatusInternalServerError)
+			Snagged(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		b := pressPage(w, r, resBuff.Bytes())
+		if pageTagged(w, r, pond, haul, b) {
 			return
 		}
 
 		w.Header().Add("Content-Type", "text/html")
-		w.Header().Add("Content-Length", strconv.Itoa(len(resBuff.Bytes())))
-		_, err = w.Write(resBuff.Bytes())
+		w.Header().Add("Content-Length", strconv.Itoa(len(b)))
+		if haul.Status != 0 {
+			w.WriteHeader(haul.Status)
+		}
+		_, err = w.Write(b)
 		if err != nil {
-			fmt.Print(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			// too late for a puffer fish, but the pond should know
+			snagWrite(w, r, f, pond, err)
 		}
 	}
 }
 
-func handlerClownAnchovy[T, K any](f *Fish[K]) http.HandlerFunc {
+func handlerClownAnchovy[T, K any](f *Fish[K], pond *Pond[T, K]) http.HandlerFunc {
+	printed := fingerprint(f.pattern, f.hash)
 	return func(w http.ResponseWriter, r *http.Request) {
-		if f.kind == FiskKindClown {
-			ver := r.URL.Query().Get("v")
-			if ver != f.hash {
-				fmt.Print("mismatched file ver request")
-				w.WriteHeader(http.StatusNotFound)
+		encoding, b := "", []byte(nil)
+		if compressible(f.mime) {
+			encoding, b = pick(r, f, pond)
+		}
+
+		// opened before any caching headers are set, so a
+		// file gone since the pond was stocked is not cached
+		var file fs.File
+		if len(encoding) == 0 {
+			var err error
+			file, err = f.fsys.Open(f.fsPath)
+			if errors.Is(err, fs.ErrNotExist) {
+				Snagged(w, r, http.StatusNotFound, err)
+				return
+			}
+			if err != nil {
+				Snagged(w, r, http.StatusInternalServerError, err)
 				return
 			}
+			defer file.Close()
 		}
 
-		file, err := os.Open(f.filePath)
-		if errors.Is(err, os.ErrNotExist) {
-			fmt.Print(err)
-			w.WriteHeader(http.StatusNotFound)
-			return
+		switch r.URL.Path {
+		case f.pattern:
+			w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", browserCacheDurationSeconds))
+		case printed:
+			w.Header().Set("Cache-Control", immutableCacheControl)
+		default:
+			// caught by a fingerprint not known, see adrift
+			w.Header().Set("Cache-Control", "no-cache")
 		}
-		if err != nil {
-			fmt.Print(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+
+		etag := etagOf(f.hash)
+		if compressible(f.mime) {
+			w.Header().Add("Vary", "Accept-Encoding")
 		}
-		defer file.Close()
-		b, err := io.ReadAll(file)
-		if err != nil {
-			fmt.Print(err)
-			w.WriteHeader(http.StatusInternalServerError)
+		if len(encoding) > 0 {
+			// each encoding is its own representation
+			etag = etagOf(f.hash + "-" + encoding)
+			w.Header().Set("Content-Encoding", encoding)
+		}
+
+		// the hash is known from when the fish was found,
+		// so a revalidation never needs to read the file
+		if tagged(w, r, etag, f.modTime) {
 			return
 		}
-		w.Header().Add("Content-Type", f.mime)
-		w.Header().Add("Content-Length", strconv.Itoa(len(b)))
-		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", browserCacheDurationSeconds))
-		_, err = w.Write(b)
-		if err != nil {
-			fmt.Print(err)
-			w.WriteHeader(http.StatusInternalServerError)
+		w.Header().Set("Content-Type", f.mime)
+
+		if file == nil {
+			http.ServeContent(w, r, f.fsPath, f.modTime, bytes.NewReader(b))
 			return
 		}
+		stream(w, r, file, f, pond)
+	}
+}
+
+// stream writes a file without reading it whole. If it can seek, as files
+// on disk and embedded are, a range of it can be asked for too.
+func stream[T, K any](w http.ResponseWriter, r *http.Request, file fs.File, f *Fish[K], pond *Pond[T, K]) {
+	if rs, ok := file.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, f.fsPath, f.modTime, rs)
+		return
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		Snagged(w, r, http.StatusInternalServerError, err)
+		return
 	}
+	w.Header().Set("Accept-Ranges", "none")
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, err = io.Copy(w, file)
+	if err != nil {
+		snagWrite(w, r, f, pond, err)
+	}
+}
+
+// snagWrite tells the pond a response could not be written.
+// Headers are sent by then, so no puffer fish is rendered.
+func snagWrite[T, K any](w http.ResponseWriter, r *http.Request, f *Fish[K], pond *Pond[T, K], err error) {
+	snag := &Snag{
+		Status:  http.StatusInternalServerError,
+		Err:     err,
+		Request: r,
+	}
+	pond.options.Logger.Log(r.Context(), slog.LevelWarn, "cannot write catch", snagAttrs(f, snag)...)
+	if pond.options.OnSnag == nil {
+		return
+	}
+	pond.options.OnSnag(w, snag)
 }
 
+// bobber gives the links of clown fish for the head of a tuna.
+// Once a bobber is determined it is saved in the fish for re use.
+// Only determined while lines are cast, so never written while a fish is caught.
 func bobber[T, K any](f *Fish[K], pond *Pond[T, K]) []byte {
 	if f.bobber != nil {
 		return f.bobber
@@ -123,17 +229,24 @@ func bobber[T, K any](f *Fish[K], pond *Pond[T, K]) []byte {
 	headLinks := make([][]byte, 0, 10)
 
 	size := 0
+
+	if pond.options.HotReloadBrowser {
+		b := []byte(reloadScript)
+		headLinks = append(headLinks, b)
+		size += len(b)
+	}
+
 	for _, e := range pond.shad {
 		if e.kind != FiskKindClown {
 			continue
 		}
 		if strings.HasPrefix(e.mime, "text/css") {
-			b := fmt.Appendf(nil, `<link rel="stylesheet" href="%s?v=%s">`, e.pattern, e.hash)
+			b := fmt.Appendf(nil, `<link rel="stylesheet" href="%s">`, fingerprint(e.pattern, e.hash))
 			headLinks = append(headLinks, b)
 			size += len(b)
 		}
 		if strings.HasPrefix(e.mime, "text/javascript") {
-			b := fmt.Appendf(nil, `<script src="%s?v=%s"></script>`, e.pattern, e.hash)
+			b := fmt.Appendf(nil, `<script src="%s"></script>`, fingerprint(e.pattern, e.hash))
 			headLinks = append(headLinks, b)
 			size += len(b)
 		}
@@ -143,12 +256,12 @@ func bobber[T, K any](f *Fish[K], pond *Pond[T, K]) []byte {
 			continue
 		}
 		if strings.HasPrefix(e.mime, "text/css") {
-			b := fmt.Appendf(nil, `<link rel="stylesheet" href="%s?v=%s">`, e.pattern, e.hash)
+			b := fmt.Appendf(nil, `<link rel="stylesheet" href="%s">`, fingerprint(e.pattern, e.hash))
 			headLinks = append(headLinks, b)
 			size += len(b)
 		}
 		if strings.HasPrefix(e.mime, "text/javascript") {
-			b := fmt.Appendf(nil, `<script src="%s?v=%s"></script>`, e.pattern, e.hash)
+			b := fmt.Appendf(nil, `<script src="%s"></script>`, fingerprint(e.pattern, e.hash))
 			headLinks = append(headLinks, b)
 			size += len(b)
 		}
@@ -170,79 +283,329 @@ func bobber[T, K any](f *Fish[K], pond *Pond[T, K]) []byte {
 	return b
 }
 
-// handlerTuna wraps a fish reef in in html5 syntax and
-// adds the bobber to the head of the document. It uses the
-// bait for a fish and its pond for template data. It uses
-// tackle for template funcs.
-func handlerTuna[T, K any](f *Fish[K], pond *Pond[T, K]) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var (
-			docStart  = []byte(`<!DOCTYPE html><html lang="en"><head><meta charset="UTF-8"><meta name="viewport" content="width=device-width, initial-scale=1.0" >`)
-			bodyStart = []byte(`</head><body>`)
-			docEnd    = []byte(`</body></html>`)
-		)
+// act runs the action of a fish if the request is not just looking.
+// Gives back an empty haul if there is nothing to do.
+func act[K any](w http.ResponseWriter, r *http.Request, f *Fish[K]) (Haul[K], error) {
+	if f.Action == nil || r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return Haul[K]{}, nil
+	}
+	return f.Action(w, r)
+}
 
-		t := template.New(f.templateName)
+// haulName is the template name of the fish rendered after an action
+func haulName[K any](f *Fish[K], haul Haul[K]) string {
+	if len(haul.Fish) == 0 {
+		return f.templateName
+	}
+	return haul.Fish
+}
 
-		reef, err := reef(f, pond)
-		if err != nil {
-			fmt.Print(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+// redirect sends a request where an action said to. An htmx request
+// would swap in the redirect, so it is told to go there instead.
+func redirect[K any](w http.ResponseWriter, r *http.Request, haul Haul[K]) {
+	if HTMX(r).Request {
+		HXRedirect(w, haul.Redirect)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	status := haul.Status
+	if status < 300 || status > 399 {
+		status = http.StatusSeeOther
+	}
+	http.Redirect(w, r, haul.Redirect, status)
+}
+
+// timed gives a request the render timeout of a pond, if it has one
+func timed[T, K any](r *http.Request, pond *Pond[T, K]) (*http.Request, context.CancelFunc) {
+	if pond.options.RenderTimeout <= 0 {
+		return r, func() {}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), pond.options.RenderTimeout)
+	return r.WithContext(ctx), cancel
+}
+
+// lureWriter is given to a lure caught alongside another, so the
+// headers they set do not race. Headers are given to the response
+// once both are caught, or before a lure writes.
+type lureWriter struct {
+	http.ResponseWriter
+	header http.Header
+	mu     *sync.Mutex
+}
+
+func (l *lureWriter) Header() http.Header {
+	return l.header
+}
+
+func (l *lureWriter) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.merge()
+	return l.ResponseWriter.Write(b)
+}
+
+func (l *lureWriter) WriteHeader(status int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.merge()
+	l.ResponseWriter.WriteHeader(status)
+}
+
+// merge gives the headers set so far to the response
+func (l *lureWriter) merge() {
+	dst := l.ResponseWriter.Header()
+	for k, vs := range l.header {
+		dst[k] = append(dst[k], vs...)
+	}
+	clear(l.header)
+}
+
+// catchBait gives the data a fish is executed with. The pond
+// lure or chum, and the fish lure or bait. A lure wins over bait,
+// and the local of a haul wins over both. Nothing is caught for a
+// request already done with, such as a client gone or a render
+// timeout passed.
+func catchBait[T, K any](w http.ResponseWriter, r *http.Request, f *Fish[K], pond *Pond[T, K], haul Haul[K]) (masterBait[T, K], error) {
+	pageData := masterBait[T, K]{}
+	done := r.Context()
+	if err := done.Err(); err != nil {
+		return pageData, err
+	}
+
+	// a puffer fish is rendered once a catch snagged, so it is not
+	// given a lure that could snag again, only chum
+	var global Lure[T]
+	if pond.Lure != nil && f.kind != FishKindPuffer {
+		global = pond.Lure
+	} else if pond.Chum != nil {
+		global = func(_ http.ResponseWriter, r *http.Request) (T, error) {
+			return pond.Chum(r), nil
 		}
+	}
 
-		if f.Tackle != nil {
-			t.Funcs(f.Tackle)
+	var local Lure[K]
+	if !reflect.ValueOf(&haul.Local).Elem().IsZero() {
+		pageData.Local = haul.Local
+	} else if f.Lure != nil {
+		local = f.Lure
+	} else if f.Bait != nil {
+		local = func(_ http.ResponseWriter, r *http.Request) (K, error) {
+			return f.Bait(r), nil
 		}
+	}
+
+	var globalBait T
+	var localBait K
+	var globalErr, localErr error
+	if global != nil && local != nil {
+		globalBait, localBait, globalErr, localErr = catchTogether(w, r, global, local)
+	} else if global != nil {
+		globalBait, globalErr = global(w, r)
+	} else if local != nil {
+		localBait, localErr = local(w, r)
+	}
+
+	if globalErr != nil {
+		return pageData, globalErr
+	}
+	if global != nil {
+		pageData.Global = globalBait
+	}
+	if localErr != nil {
+		return pageData, localErr
+	}
+	if local != nil {
+		pageData.Local = localBait
+	}
+	return pageData, done.Err()
+}
+
+// formMaxMemory is how much of a multipart form is kept in
+// memory when parsed before lures are caught, as net/http does
+const formMaxMemory = 32 << 20
+
+// catchTogether catches global and local bait at the same time, since
+// neither needs the other. If either snags the other is told with its
+// request context, and the error that snagged first is the one given.
+// A panic in either is given back to the catch, where the server can
+// recover it.
+//
+// The form is parsed once before, since parsing it is not safe to do
+// from both. The global lure is given its own request with no body,
+// the body is left for the local lure.
+func catchTogether[T, K any](w http.ResponseWriter, r *http.Request, global Lure[T], local Lure[K]) (T, K, error, error) {
+	// an error is left for the lures to find, as FormValue would
+	r.ParseMultipartForm(formMaxMemory)
+
+	ctx, cancel := context.WithCancelCause(r.Context())
+	defer cancel(nil)
+	globalR := r.Clone(ctx)
+	globalR.Body = http.NoBody
+	r = r.WithContext(ctx)
+
+	mu := &sync.Mutex{}
+	globalW := &lureWriter{ResponseWriter: w, header: http.Header{}, mu: mu}
+	localW := &lureWriter{ResponseWriter: w, header: http.Header{}, mu: mu}
+
+	var globalBait T
+	var globalErr error
+	var panicked any
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() {
+			panicked = recover()
+			if panicked != nil {
+				cancel(nil)
+			}
+		}()
+		globalBait, globalErr = global(globalW, globalR)
+		if globalErr != nil {
+			cancel(globalErr)
+		}
+	}()
+
+	var localBait K
+	var localErr error
+	func() {
+		defer func() {
+			if p := recover(); p != nil {
+				cancel(nil)
+				wg.Wait()
+				panic(p)
+			}
+		}()
+		localBait, localErr = local(localW, r)
+		if localErr != nil {
+			cancel(localErr)
+		}
+	}()
+
+	wg.Wait()
+	if panicked != nil {
+		panic(panicked)
+	}
+	if localErr != nil && context.Cause(ctx) == localErr {
+		// the global was told to give up, so its error is not its own
+		globalErr = nil
+	}
+
+	mu.Lock()
+	globalW.merge()
+	localW.merge()
+	mu.Unlock()
+	return globalBait, localBait, globalErr, localErr
+}
+
+// swallow executes a fish that is served as a page and has it
+// swallowed by the nearest whale shark (layout), giving it the
+// bobber and the head block for the head of the document.
+func swallow[T, K any](f *Fish[K], pageData masterBait[T, K]) ([]byte, error) {
+	body := bytes.NewBuffer(nil)
+	err := f.hooked.ExecuteTemplate(body, f.templateName, pageData)
+	if err != nil {
+		return nil, err
+	}
 
-		parsed, err := t.Parse(string(reef))
+	head := bytes.NewBuffer(nil)
+	if hasHead(f) {
+		err = f.hooked.ExecuteTemplate(head, headTemplateName, pageData)
 		if err != nil {
-			fmt.Print(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			return nil, err
 		}
+	}
+
+	whole := wholeTuna[T, K]{
+		Bobber: template.HTML(f.bobber),
+		Head:   template.HTML(head.Bytes()),
+		Body:   template.HTML(body.Bytes()),
+		Local:  pageData.Local,
+		Global: pageData.Global,
+		Snag:   pageData.Snag,
+	}
 
-		headLinks := bobber(f, pond)
+	// this size is not perfect since the layout size
+	// cannot be know, but it helps some allocation before that
+	buff := bytes.NewBuffer(make([]byte, 0, len(f.bobber)+head.Len()+body.Len()))
+	err = f.hooked.ExecuteTemplate(buff, layoutTemplateName, whole)
+	if err != nil {
+		return nil, err
+	}
+	return buff.Bytes(), nil
+}
+
+// handlerTuna swallows a tuna whole. It uses the bait for a
+// fish and its pond for template data. It uses tackle for
+// template funcs.
+func handlerTuna[T, K any](f *Fish[K], pond *Pond[T, K]) http.HandlerFunc {
+	_, hookErr := hook(f, pond)
+	bobber(f, pond)
+	if hookErr == nil && pond.options.StreamHead {
+		f.prelude = prelude[T](f)
+	}
 
-		// this size is not perfect since the executed template size
-		// cannot be know, but it helps some allocation before that
-		size := len(docStart) + len(headLinks) + len(bodyStart) + len(docEnd)
+	return func(w http.ResponseWriter, r *http.Request) {
+		r, cancel := timed(r, pond)
+		defer cancel()
 
-		buff := bytes.NewBuffer(make([]byte, 0, size))
-		buff.Write(docStart)
-		buff.Write(headLinks)
-		buff.Write(bodyStart)
+		if hookErr != nil {
+			Snagged(w, r, http.StatusInternalServerError, hookErr)
+			return
+		}
 
-		var globalBait T
-		var localBait K
-		if f.Bait != nil {
-			localBait = f.Bait(r)
+		haul, err := act(w, r, f)
+		if err != nil {
+			baitSnagged(w, r, err)
+			return
 		}
-		if pond.Chum != nil {
-			globalBait = pond.Chum(r)
+		if len(haul.Redirect) > 0 {
+			redirect(w, r, haul)
+			return
 		}
-		pageData := masterBait[T, K]{
-			Local:  localBait,
-			Global: globalBait,
+		if streams(r, f, pond, haul) {
+			streamTuna(w, r, f, pond, haul)
+			return
 		}
 
-		err = parsed.ExecuteTemplate(buff, f.templateName, pageData)
+		pageData, err := catchBait(w, r, f, pond, haul)
 		if err != nil {
-			fmt.Print(err)
-			w.WriteHeader(http.StatusInternalServerError)
+			baitSnagged(w, r, err)
+			return
+		}
+
+		var b []byte
+		if name := haulName(f, haul); name != f.templateName || bodyOnly(r) {
+			// another fish from the reef, or a tuna swapped in
+			// by htmx, is rendered on its own
+			buff := bytes.NewBuffer(nil)
+			err = f.hooked.ExecuteTemplate(buff, name, pageData)
+			b = buff.Bytes()
+		} else {
+			b, err = swallow(f, pageData)
+		}
+		if err != nil {
+			Snagged(w, r, http.StatusInternalServerError, err)
 			return
 		}
 
-		buff.Write(docEnd)
+		w.Header().Add("Vary", bodyOnlyVary)
+		if !pond.options.PageETags {
+			w.Header().Add("Cache-Control", "no-store")
+		}
+		b = pressPage(w, r, b)
+		if pageTagged(w, r, pond, haul, b) {
+			return
+		}
 
-		w.Header().Add("Cache-Control", "no-store")
 		w.Header().Add("Content-Type", "text/html")
-		w.Header().Add("Content-Length", strconv.Itoa(len(buff.Bytes())))
-		_, err = w.Write(buff.Bytes())
+		w.Header().Add("Content-Length", strconv.Itoa(len(b)))
+		if haul.Status != 0 {
+			w.WriteHeader(haul.Status)
+		}
+		_, err = w.Write(b)
 		if err != nil {
-			fmt.Print(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			snagWrite(w, r, f, pond, err)
 		}
 	}
 }
@@ -258,9 +621,8 @@ func chainLicenses(fin http.Handler, licenses ...License) http.Handler {
 	return fin
 }
 
-// reel enables catching a fish. It will chain license
-// together to ensure you are allowed to catch
-func reel[T, K any](f *Fish[K], pond *Pond[T, K]) http.Handler {
+// licensesOf gives the licenses to catch a fish, pond licenses first
+func licensesOf[T, K any](f *Fish[K], pond *Pond[T, K]) []License {
 	licenses := []License{}
 
 	for _, license := range pond.licenses {
@@ -270,30 +632,36 @@ func reel[T, K any](f *Fish[K], pond *Pond[T, K]) http.Handler {
 	for _, license := range f.Licenses {
 		licenses = append(licenses, license)
 	}
+	return licenses
+}
 
-	var (
-		cannotCatch = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+// reel enables catching a fish. It will chain license
+// together to ensure you are allowed to catch. Anything
+// that snags along the way is given to a puffer fish.
+func reel[T, K any](f *Fish[K], pond *Pond[T, K]) http.Handler {
+	licenses := licensesOf(f, pond)
+
+	var finalHandler http.Handler
+	switch f.kind {
+	case FishKindSardine:
+		finalHandler = handlerSardine(f, pond)
+	case FiskKindClown, FiskKindAnchovy:
+		finalHandler = handlerClownAnchovy(f, pond)
+	case FishKindBreed:
+		finalHandler = handlerBreed(f, pond)
+	case FishKindTuna:
+		finalHandler = handlerTuna(f, pond)
+	case FishKindMackerel, FishKindWhaleShark, FishKindPuffer:
+		finalHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 			w.WriteHeader(http.StatusNotAcceptable)
 			w.Write([]byte("this fish is for catching"))
 		})
-		unaccountedFish = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	default:
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("this fish was not accounted for"))
 		})
-	)
-
-	handlerMap := map[int]http.HandlerFunc{
-		FishKindSardine:  handlerSardine(f, pond),
-		FiskKindClown:    handlerClownAnchovy[T](f),
-		FiskKindAnchovy:  handlerClownAnchovy[T](f),
-		FishKindTuna:     handlerTuna(f, pond),
-		FishKindMackerel: cannotCatch,
-	}
-
-	finalHandler, exists := handlerMap[f.kind]
-	if !exists {
-		return unaccountedFish
 	}
 
-	return chainLicenses(finalHandler, licenses...)
+	return withSnag(withParamTypes(chainLicenses(finalHandler, licenses...), f), f, pond)
 }
@@ -0,0 +1,594 @@
+package aquatic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/Isaac799/go-fish/pkg/bridge"
+)
+
+var (
+	errNotEqual         = errors.New("a and b where not equal")
+	errUnexpectedStatus = errors.New("status was not as expected")
+	errMissingContent   = errors.New("body did not contain expected content")
+	errFoundContent     = errors.New("body contained unexpected content")
+	errUnexpectedValue  = errors.New("value was not as expected")
+)
+
+type mockGlobal struct{}
+
+type mockLocal struct {
+	Name string
+	El   *bridge.HTMLElement
+}
+
+// mockPond provides a pond from test data where every fish
+// is baited with the local given
+func mockPond(t testing.TB, options NewPondOptions, local mockLocal) *Pond[mockGlobal, mockLocal] {
+	pond, err := NewPond[mockGlobal, mockLocal]("testdata/pond", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	StockPond(&pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile(".html"): {
+			Bait: func(_ *http.Request) mockLocal {
+				return local
+			},
+		},
+	})
+	return &pond
+}
+
+// mockCatch serves a request against a mux, providing the response
+func mockCatch(mux http.Handler, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, target, nil)
+	mux.ServeHTTP(w, r)
+	return w
+}
+
+func assertContains(t *testing.T, body, s string) {
+	t.Helper()
+	if strings.Contains(body, s) {
+		return
+	}
+	t.Log(body)
+	t.Fatal(errMissingContent, s)
+}
+
+func assertNotContains(t *testing.T, body, s string) {
+	t.Helper()
+	if !strings.Contains(body, s) {
+		return
+	}
+	t.Log(body)
+	t.Fatal(errFoundContent, s)
+}
+
+func assertStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code == status {
+		return
+	}
+	t.Log(w.Body.String())
+	t.Fatal(errUnexpectedStatus, w.Code, status)
+}
+
+func TestHandlerTuna_EscapesBait(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{Name: `<script>alert("hi")</script>`})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusOK)
+
+	body := w.Body.String()
+	assertContains(t, body, "&lt;script&gt;")
+	assertNotContains(t, body, "<script>alert")
+	assertContains(t, body, `<nav><a href="/">Home</a></nav>`)
+}
+
+func TestHandlerSardine_EscapesBait(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{Name: `<i>x</i>`})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/user/_card")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), "<b>&lt;i&gt;x&lt;/i&gt;</b>")
+}
+
+func TestHandlerTuna_RawOutput(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{RawOutput: true}, mockLocal{Name: `<i>trusted</i>`})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), "<main><i>trusted</i></main>")
+}
+
+func TestMackerelElement_EscapesAttributes(t *testing.T) {
+	el := bridge.NewHTMLElement("a")
+	el.Attributes["title"] = `"><script>`
+	el.Attributes["href"] = "javascript:alert(1)"
+	el.Attributes[`bad" name`] = "x"
+	el.InnerText = "<b>link</b>"
+
+	input := bridge.NewHTMLElement("input")
+	input.SelfClosing = true
+	input.Attributes["value"] = "a&b"
+	input.Attributes["onfocus"] = "alert(1)"
+	input.Attributes["style"] = "background:url(javascript:alert(1))"
+	el.Children = append(el.Children, input)
+
+	styled := bridge.NewHTMLElement("p")
+	styled.Attributes["style"] = "color: red; margin:0;"
+	styled.Attributes["data-onclick"] = "alert(1)"
+	el.Children = append(el.Children, styled)
+
+	pond := mockPond(t, NewPondOptions{}, mockLocal{El: &el})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusOK)
+
+	body := w.Body.String()
+	assertContains(t, body, `<a href="#ZgotmplZ" title="&#34;&gt;&lt;script&gt;">&lt;b&gt;link&lt;/b&gt; <input onfocus="ZgotmplZ" style="ZgotmplZ" value="a&amp;b" /> <p data-onclick="ZgotmplZ" style="color: red; margin:0;"></p></a>`)
+	assertNotContains(t, body, "bad")
+}
+
+// mockFish finds a fish in a pond by its template name
+func mockFish[T, K any](t testing.TB, pond *Pond[T, K], templateName string) *Fish[K] {
+	for _, f := range FishFinder(pond) {
+		if f.templateName == templateName {
+			return f
+		}
+	}
+	t.Fatal("no fish named " + templateName)
+	return nil
+}
+
+func BenchmarkHandlerTuna(b *testing.B) {
+	pond := mockPond(b, NewPondOptions{}, mockLocal{Name: "bench"})
+	handler := handlerTuna(mockFish(b, pond, "pond"), pond)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	b.ResetTimer()
+	for range b.N {
+		handler(httptest.NewRecorder(), r)
+	}
+}
+
+// BenchmarkHandlerTuna_Unhooked parses the reef on every catch,
+// which is how tuna used to be caught
+func BenchmarkHandlerTuna_Unhooked(b *testing.B) {
+	pond := mockPond(b, NewPondOptions{}, mockLocal{Name: "bench"})
+	f := mockFish(b, pond, "pond")
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	b.ResetTimer()
+	for range b.N {
+		f.hooked = nil
+		handlerTuna(f, pond)(httptest.NewRecorder(), r)
+	}
+}
+
+func TestHandlerTuna_Layout(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{Name: "<Sally>"})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), `<!DOCTYPE html><html lang="en"><head><meta charset="UTF-8">`)
+	assertContains(t, w.Body.String(), `</main>`)
+
+	w = mockCatch(mux, http.MethodGet, "/user/3")
+	assertStatus(t, w, http.StatusOK)
+	body := w.Body.String()
+	assertContains(t, body, `<html lang="nl"><head><link rel="stylesheet" href="/style.`)
+	assertContains(t, body, `<title>&lt;Sally&gt;</title></head><body class="user"><h1>User &lt;Sally&gt;</h1>`)
+	assertNotContains(t, body, `lang="en"`)
+}
+
+func TestHandlerTuna_LayoutGlobal(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/ux.html":            {Data: []byte(`<p>home</p>`)},
+		"ux/_layout.html":       {Data: []byte(`<html class="global">{{ .Body }}</html>`)},
+		"ux/a/a.html":           {Data: []byte(`<p>a</p>`)},
+		"ux/b/b.html":           {Data: []byte(`<p>b</p>`)},
+		"ux/b/_layout.html":     {Data: []byte(`<html class="b">{{ .Body }}</html>`)},
+		"ux/b/_not_layout.html": {Data: []byte(`<p>sardine</p>`)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := CastLines(&pond, false)
+
+	tests := map[string]string{
+		"/":  `<html class="global"><p>home</p></html>`,
+		"/a": `<html class="global"><p>a</p></html>`,
+		"/b": `<html class="b"><p>b</p></html>`,
+	}
+	for target, expected := range tests {
+		w := mockCatch(mux, http.MethodGet, target)
+		assertStatus(t, w, http.StatusOK)
+		if w.Body.String() != expected {
+			t.Fatal(errNotEqual, target, w.Body.String(), expected)
+		}
+	}
+
+	w := mockCatch(mux, http.MethodGet, "/b/_not_layout")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), "<p>sardine</p>")
+}
+
+func TestHandlerTuna_Head(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{Name: `"Tuna" & <Sardine>`})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusOK)
+	body := w.Body.String()
+
+	style := strings.Index(body, `<link rel="stylesheet"`)
+	title := strings.Index(body, `<title>&#34;Tuna&#34; &amp; &lt;Sardine&gt;</title>`)
+	meta := strings.Index(body, `<meta name="description" content="&#34;Tuna&#34; &amp; &lt;Sardine&gt;">`)
+	headEnd := strings.Index(body, `</head>`)
+	if style == -1 || title == -1 || meta == -1 {
+		t.Log(body)
+		t.Fatal(errMissingContent)
+	}
+	if !(style < title && title < meta && meta < headEnd) {
+		t.Log(body)
+		t.Fatal(errUnexpectedValue)
+	}
+
+	// head is not part of the body
+	assertContains(t, body, `<body>
+<header>`)
+}
+
+func TestHandlerTuna_NoHead(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{Name: "x"})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/user")
+	assertStatus(t, w, http.StatusOK)
+	assertNotContains(t, w.Body.String(), `<meta name="description"`)
+}
+
+func TestHandlerTuna_NamedHead(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/ux.html":   {Data: []byte(`<p>home</p>`)},
+		"ux/head.html": {Data: []byte(`<p>a tuna named head</p>`)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := CastLines(&pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/head")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), `<body><p>a tuna named head</p></body>`)
+	// only in the body, not the head too
+	if strings.Count(w.Body.String(), "a tuna named head") != 1 {
+		t.Fatal(errUnexpectedValue, w.Body.String())
+	}
+}
+
+func TestHandlerTuna_Lure(t *testing.T) {
+	errBroken := errors.New("broken")
+	tests := map[string]struct {
+		err      error
+		status   int
+		contains string
+	}{
+		"ok":        {nil, http.StatusOK, "<h1>Users</h1>"},
+		"not found": {ErrNotFound, http.StatusNotFound, `<main class="user-lost">no user </main>`},
+		"wrapped":   {fmt.Errorf("no user 9: %w", ErrForbidden), http.StatusForbidden, ""},
+		"snag":      {&Snag{Status: http.StatusGone}, http.StatusGone, ""},
+		"broken":    {errBroken, http.StatusInternalServerError, ""},
+		"redirect":  {Redirect("/login", 0), http.StatusSeeOther, `<a href="/login">`},
+	}
+
+	for name, tt := range tests {
+		pond := mockPond(t, NewPondOptions{}, mockLocal{Name: "bait"})
+		StockPond(pond, Stock[mockGlobal, mockLocal]{
+			regexp.MustCompile("user/user.html"): {
+				Lure: func(w http.ResponseWriter, _ *http.Request) (mockLocal, error) {
+					http.SetCookie(w, &http.Cookie{Name: "seen", Value: "yes"})
+					return mockLocal{Name: "lure"}, tt.err
+				},
+			},
+		})
+		mux := CastLines(pond, false)
+
+		w := mockCatch(mux, http.MethodGet, "/user")
+		if w.Code != tt.status {
+			t.Fatal(errUnexpectedStatus, name, w.Code)
+		}
+		assertContains(t, w.Body.String(), tt.contains)
+		if w.Header().Get("Set-Cookie") != "seen=yes" {
+			t.Fatal(errUnexpectedValue, name, w.Header())
+		}
+	}
+}
+
+func TestHandlerTuna_LureWinsOverBait(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{Name: "bait"})
+	StockPond(pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("user/.id.html"): {
+			Lure: func(_ http.ResponseWriter, r *http.Request) (mockLocal, error) {
+				return mockLocal{Name: "lure " + r.PathValue("id")}, nil
+			},
+		},
+	})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/user/3")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), "<h1>User lure 3</h1>")
+}
+
+func TestHandlerSardine_PondLure(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{})
+	pond.Lure = func(_ http.ResponseWriter, _ *http.Request) (mockGlobal, error) {
+		return mockGlobal{}, ErrUnauthorized
+	}
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/user/_card")
+	assertStatus(t, w, http.StatusUnauthorized)
+}
+
+func TestHandlerTuna_Action(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{Name: "bait"})
+	StockPond(pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("user/user.html"): {
+			Action: func(_ http.ResponseWriter, r *http.Request) (Haul[mockLocal], error) {
+				r.ParseForm()
+				switch r.Form.Get("do") {
+				case "invalid":
+					return Haul[mockLocal]{Local: mockLocal{Name: "invalid"}, Status: http.StatusUnprocessableEntity}, nil
+				case "card":
+					return Haul[mockLocal]{Local: mockLocal{Name: "saved"}, Fish: "_card"}, nil
+				case "forbid":
+					return Haul[mockLocal]{}, ErrForbidden
+				case "away":
+					return Haul[mockLocal]{Redirect: "/user/1"}, nil
+				}
+				return Haul[mockLocal]{}, nil
+			},
+		},
+	})
+	mux := CastLines(pond, false)
+
+	post := func(do string, htmx bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/user", strings.NewReader("do="+do))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if htmx {
+			r.Header.Set("HX-Request", "true")
+		}
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	w := post("invalid", false)
+	assertStatus(t, w, http.StatusUnprocessableEntity)
+	assertContains(t, w.Body.String(), "<title>invalid</title>")
+
+	w = post("card", false)
+	assertStatus(t, w, http.StatusOK)
+	if w.Body.String() != "<b>saved</b>" {
+		t.Fatal(errNotEqual, w.Body.String())
+	}
+
+	// nothing in the haul falls back to bait
+	w = post("", false)
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), "<title>bait</title>")
+
+	w = post("forbid", false)
+	assertStatus(t, w, http.StatusForbidden)
+
+	w = post("away", false)
+	assertStatus(t, w, http.StatusSeeOther)
+	if w.Header().Get("Location") != "/user/1" {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+
+	w = post("away", true)
+	assertStatus(t, w, http.StatusOK)
+	if w.Header().Get("HX-Redirect") != "/user/1" || w.Body.Len() != 0 {
+		t.Fatal(errUnexpectedValue, w.Header(), w.Body.String())
+	}
+
+	// just looking does not act
+	w = mockCatch(mux, http.MethodGet, "/user?do=forbid")
+	assertStatus(t, w, http.StatusOK)
+}
+
+func TestHandlerSardine_Action(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{Name: "bait"})
+	StockPond(pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("_card"): {
+			Methods: []string{http.MethodGet, http.MethodDelete},
+			Action: func(_ http.ResponseWriter, r *http.Request) (Haul[mockLocal], error) {
+				return Haul[mockLocal]{Local: mockLocal{Name: "deleted"}}, nil
+			},
+		},
+	})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodDelete, "/user/_card")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), "<b>deleted</b>")
+
+	w = mockCatch(mux, http.MethodGet, "/user/_card")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), "<b>bait</b>")
+}
+
+func TestCatchBait_Together(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{})
+
+	// each waits on the other, so only caught at the same time
+	met := make(chan struct{})
+	pond.Lure = func(w http.ResponseWriter, _ *http.Request) (mockGlobal, error) {
+		w.Header().Set("X-Global", "chum")
+		met <- struct{}{}
+		return mockGlobal{}, nil
+	}
+	StockPond(pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("user/user.html"): {
+			Lure: func(w http.ResponseWriter, _ *http.Request) (mockLocal, error) {
+				http.SetCookie(w, &http.Cookie{Name: "seen", Value: "yes"})
+				select {
+				case <-met:
+				case <-time.After(time.Second):
+					return mockLocal{}, errors.New("caught alone")
+				}
+				return mockLocal{Name: "together"}, nil
+			},
+		},
+	})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/user")
+	assertStatus(t, w, http.StatusOK)
+	if w.Header().Get("X-Global") != "chum" || w.Header().Get("Set-Cookie") != "seen=yes" {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+}
+
+func TestCatchBait_TogetherForm(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{})
+
+	// both read the form, run with -race to see they do not race,
+	// with a fish lure as the pond mock already gives bait
+	a, b := "", ""
+	pond.Chum = func(r *http.Request) mockGlobal {
+		a = r.FormValue("a")
+		return mockGlobal{}
+	}
+	StockPond(pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("user/user.html"): {
+			Methods: []string{http.MethodGet, http.MethodPost},
+			Lure: func(_ http.ResponseWriter, r *http.Request) (mockLocal, error) {
+				b = r.FormValue("b")
+				return mockLocal{}, nil
+			},
+		},
+	})
+	mux := CastLines(pond, false)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/user", strings.NewReader("a=chum&b=bait"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	mux.ServeHTTP(w, r)
+	assertStatus(t, w, http.StatusOK)
+	if a != "chum" || b != "bait" {
+		t.Fatal(errUnexpectedValue, a, b)
+	}
+}
+
+func TestCatchBait_Snag(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{})
+
+	// the global gives up once the local snags
+	var gaveUp error
+	pond.Lure = func(_ http.ResponseWriter, r *http.Request) (mockGlobal, error) {
+		<-r.Context().Done()
+		gaveUp = context.Cause(r.Context())
+		return mockGlobal{}, r.Context().Err()
+	}
+	StockPond(pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("user/user.html"): {
+			Lure: func(_ http.ResponseWriter, _ *http.Request) (mockLocal, error) {
+				return mockLocal{}, ErrNotFound
+			},
+		},
+	})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/user")
+	assertStatus(t, w, http.StatusNotFound)
+	if !errors.Is(gaveUp, ErrNotFound) {
+		t.Fatal(errUnexpectedValue, gaveUp)
+	}
+}
+
+func TestCatchBait_Panic(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{Name: "bait"})
+	pond.Chum = func(_ *http.Request) mockGlobal {
+		panic("chum")
+	}
+	mux := CastLines(pond, false)
+
+	defer func() {
+		if r := recover(); r != "chum" {
+			t.Fatal(errUnexpectedValue, r)
+		}
+	}()
+	mockCatch(mux, http.MethodGet, "/user")
+	t.Fatal(errors.New("did not panic"))
+}
+
+func TestCatchBait_RenderTimeout(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{RenderTimeout: 20 * time.Millisecond}, mockLocal{})
+
+	var gaveUp error
+	StockPond(pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("user/user.html"): {
+			Lure: func(_ http.ResponseWriter, r *http.Request) (mockLocal, error) {
+				<-r.Context().Done()
+				gaveUp = r.Context().Err()
+				return mockLocal{Name: "late"}, nil
+			},
+		},
+	})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/user")
+	assertStatus(t, w, http.StatusServiceUnavailable)
+	assertNotContains(t, w.Body.String(), "late")
+	if !errors.Is(gaveUp, context.DeadlineExceeded) {
+		t.Fatal(errUnexpectedValue, gaveUp)
+	}
+
+	// quick enough
+	w = mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusOK)
+}
+
+func TestCatchBait_ClientGone(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{})
+	caught := false
+	StockPond(pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("user/user.html"): {
+			Lure: func(_ http.ResponseWriter, _ *http.Request) (mockLocal, error) {
+				caught = true
+				return mockLocal{}, nil
+			},
+		},
+	})
+	mux := CastLines(pond, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user", nil).WithContext(ctx))
+	assertStatus(t, w, statusClientClosed)
+	if caught {
+		t.Fatal(errUnexpectedValue, "bait caught for a client gone")
+	}
+}
@@ -0,0 +1,92 @@
+package aquatic
+
+import (
+	"net/http"
+	"strings"
+)
+
+// HX is what htmx told about a request in its headers
+type HX struct {
+	// Request is true for any request htmx made
+	Request bool
+	// Boosted is true for a request from a boosted link or form,
+	// which swaps the whole body so is given the whole document
+	Boosted bool
+	// Target is the id of the element swapped, if it has one
+	Target string
+	// Trigger is the id of the element that made the request, if it has one
+	Trigger string
+	// TriggerName is the name of the element that made the request, if it has one
+	TriggerName string
+	// CurrentURL is the url of the browser when the request was made
+	CurrentURL string
+	// Prompt is the answer to an hx-prompt
+	Prompt string
+	// HistoryRestoreRequest is true when restoring history after a cache miss
+	HistoryRestoreRequest bool
+}
+
+// HTMX reads what htmx told about a request. Useful in bait, a lure, or an action.
+func HTMX(r *http.Request) HX {
+	return HX{
+		Request:               r.Header.Get("HX-Request") == "true",
+		Boosted:               r.Header.Get("HX-Boosted") == "true",
+		Target:                r.Header.Get("HX-Target"),
+		Trigger:               r.Header.Get("HX-Trigger"),
+		TriggerName:           r.Header.Get("HX-Trigger-Name"),
+		CurrentURL:            r.Header.Get("HX-Current-URL"),
+		Prompt:                r.Header.Get("HX-Prompt"),
+		HistoryRestoreRequest: r.Header.Get("HX-History-Restore-Request") == "true",
+	}
+}
+
+// bodyOnlyVary are the request headers bodyOnly reads, so a cache never
+// gives a page rendered without its document to a request wanting it
+const bodyOnlyVary = "HX-Request, HX-Target, HX-Boosted, HX-History-Restore-Request"
+
+// bodyOnly is true when htmx swaps part of a page, so a tuna is
+// rendered without being swallowed (no document, bobber, or head)
+func bodyOnly(r *http.Request) bool {
+	hx := HTMX(r)
+	return hx.Request && len(hx.Target) > 0 && !hx.Boosted && !hx.HistoryRestoreRequest
+}
+
+// HXTrigger has htmx trigger events on the client once swapped
+func HXTrigger(w http.ResponseWriter, events ...string) {
+	w.Header().Set("HX-Trigger", strings.Join(events, ", "))
+}
+
+// HXRetarget has htmx swap a different element than the one requested
+func HXRetarget(w http.ResponseWriter, selector string) {
+	w.Header().Set("HX-Retarget", selector)
+}
+
+// HXReswap has htmx swap in a different way than the one requested, such as outerHTML
+func HXReswap(w http.ResponseWriter, swap string) {
+	w.Header().Set("HX-Reswap", swap)
+}
+
+// HXPushURL has htmx push a url into the browser history
+func HXPushURL(w http.ResponseWriter, url string) {
+	w.Header().Set("HX-Push-Url", url)
+}
+
+// HXReplaceURL has htmx replace the current url in the browser location bar
+func HXReplaceURL(w http.ResponseWriter, url string) {
+	w.Header().Set("HX-Replace-Url", url)
+}
+
+// HXRedirect has htmx do a full page redirect
+func HXRedirect(w http.ResponseWriter, url string) {
+	w.Header().Set("HX-Redirect", url)
+}
+
+// HXLocation has htmx redirect without a full page reload
+func HXLocation(w http.ResponseWriter, url string) {
+	w.Header().Set("HX-Location", url)
+}
+
+// HXRefresh has htmx do a full page refresh
+func HXRefresh(w http.ResponseWriter) {
+	w.Header().Set("HX-Refresh", "true")
+}
@@ -0,0 +1,105 @@
+package aquatic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+// mockHTMX serves a request against a mux with the htmx headers given
+func mockHTMX(mux http.Handler, target string, headers map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	mux.ServeHTTP(w, r)
+	return w
+}
+
+func TestHTMX(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("HX-Request", "true")
+	r.Header.Set("HX-Target", "main")
+	r.Header.Set("HX-Trigger-Name", "q")
+	r.Header.Set("HX-Current-URL", "http://localhost/season")
+
+	hx := HTMX(r)
+	expected := HX{
+		Request:     true,
+		Target:      "main",
+		TriggerName: "q",
+		CurrentURL:  "http://localhost/season",
+	}
+	if hx != expected {
+		t.Fatal(errNotEqual, hx, expected)
+	}
+}
+
+func TestHandlerTuna_HTMX(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{Name: "swap"})
+	mux := CastLines(pond, false)
+
+	w := mockHTMX(mux, "/user", map[string]string{
+		"HX-Request": "true",
+		"HX-Target":  "main",
+	})
+	assertStatus(t, w, http.StatusOK)
+	if w.Body.String() != "<h1>Users</h1>" {
+		t.Fatal(errNotEqual, w.Body.String())
+	}
+	if w.Header().Get("Vary") != "HX-Request, HX-Target, HX-Boosted, HX-History-Restore-Request" {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+
+	// a boosted request swaps the whole body, and one
+	// without a target has nowhere to put part of a page
+	for _, headers := range []map[string]string{
+		{"HX-Request": "true", "HX-Target": "main", "HX-Boosted": "true"},
+		{"HX-Request": "true"},
+		{},
+	} {
+		w = mockHTMX(mux, "/user", headers)
+		assertStatus(t, w, http.StatusOK)
+		assertContains(t, w.Body.String(), "<title>swap</title>")
+		if w.Header().Get("Vary") != "HX-Request, HX-Target, HX-Boosted, HX-History-Restore-Request" {
+			t.Fatal(errUnexpectedValue, headers, w.Header())
+		}
+	}
+}
+
+func TestHandlerTuna_HTMXResponseHeaders(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{})
+	StockPond(pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("user/user.html"): {
+			Lure: func(w http.ResponseWriter, r *http.Request) (mockLocal, error) {
+				if HTMX(r).Request {
+					HXTrigger(w, "caught", "reeled")
+					HXPushURL(w, "/user?page=2")
+					HXReswap(w, "outerHTML")
+				}
+				return mockLocal{}, nil
+			},
+		},
+	})
+	mux := CastLines(pond, false)
+
+	w := mockHTMX(mux, "/user", map[string]string{"HX-Request": "true"})
+	assertStatus(t, w, http.StatusOK)
+	expected := map[string]string{
+		"HX-Trigger":  "caught, reeled",
+		"HX-Push-Url": "/user?page=2",
+		"HX-Reswap":   "outerHTML",
+	}
+	for k, v := range expected {
+		if w.Header().Get(k) != v {
+			t.Fatal(errUnexpectedValue, k, w.Header().Get(k))
+		}
+	}
+
+	w = mockCatch(mux, http.MethodGet, "/user")
+	if w.Header().Get("HX-Trigger") != "" {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+}
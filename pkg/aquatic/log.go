@@ -0,0 +1,45 @@
+package aquatic
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// discardLogger is used when a pond is not given a logger,
+// so nothing is written unless asked for
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// fishAttrs are what a log needs to know which fish it is about
+func fishAttrs[K any](f *Fish[K]) []any {
+	return []any{
+		slog.String("pattern", f.pattern),
+		slog.String("kind", kindName(f)),
+		slog.String("file", f.scopedFilePath),
+	}
+}
+
+// snagAttrs are what a log needs to know about a snagged catch
+func snagAttrs[K any](f *Fish[K], snag *Snag) []any {
+	attrs := fishAttrs(f)
+	attrs = append(attrs, slog.Int("status", snag.Status))
+	if snag.Request != nil {
+		attrs = append(attrs,
+			slog.String("method", snag.Request.Method),
+			slog.String("path", snag.Request.URL.Path),
+		)
+	}
+	if snag.Err != nil {
+		attrs = append(attrs, slog.Any("err", snag.Err))
+	}
+	return attrs
+}
+
+// snagLevel is how loud a snag is logged. Only the
+// server being at fault is an error.
+func snagLevel(snag *Snag) slog.Level {
+	if snag.Status >= http.StatusInternalServerError {
+		return slog.LevelError
+	}
+	return slog.LevelDebug
+}
@@ -0,0 +1,98 @@
+package aquatic
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// mockLogs is written to by a json logger, safe for
+// handlers to log to while a test reads
+type mockLogs struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (m *mockLogs) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.buf.Write(p)
+}
+
+// find gives the first log with the message given
+func (m *mockLogs) find(t *testing.T, msg string) map[string]any {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, line := range bytes.Split(m.buf.Bytes(), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		entry := map[string]any{}
+		err := json.Unmarshal(line, &entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry["msg"] == msg {
+			return entry
+		}
+	}
+	t.Log(m.buf.String())
+	t.Fatal(errMissingContent, msg)
+	return nil
+}
+
+func TestLogger_Snag(t *testing.T) {
+	logs := &mockLogs{}
+	fsys := fstest.MapFS{
+		"ux/ux.html":  {Data: []byte(`<p>home</p>`)},
+		"ux/b/b.html": {Data: []byte(`{{ .Local.Missing }}`)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{
+		Logger: slog.New(slog.NewJSONHandler(logs, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	StockPond(&pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("nothing-here"): {},
+	})
+	mux := CastLines(&pond, true)
+
+	entry := logs.find(t, "did not find matching fish for regex")
+	if entry["regex"] != "nothing-here" {
+		t.Fatal(errUnexpectedValue, entry)
+	}
+
+	entry = logs.find(t, "cast line")
+	if entry["level"] != "INFO" {
+		t.Fatal(errUnexpectedValue, entry)
+	}
+
+	w := mockCatch(mux, http.MethodGet, "/b")
+	assertStatus(t, w, http.StatusInternalServerError)
+
+	entry = logs.find(t, "snagged")
+	expected := map[string]any{
+		"level":   "ERROR",
+		"pattern": "/b",
+		"kind":    "Tuna",
+		"file":    "/b/b.html",
+		"status":  float64(http.StatusInternalServerError),
+		"method":  http.MethodGet,
+		"path":    "/b",
+	}
+	for k, v := range expected {
+		if entry[k] != v {
+			t.Fatal(errUnexpectedValue, k, entry[k], v)
+		}
+	}
+	if entry["err"] == nil {
+		t.Fatal(errUnexpectedValue, entry)
+	}
+}
@@ -0,0 +1,122 @@
+package aquatic
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// paramTypes are the types a path value can be given in a file name,
+// such as `user.id@int.html`, with how a value is checked to be one
+var paramTypes = map[string]func(s string) error{
+	"int": func(s string) error {
+		_, err := strconv.ParseInt(s, 10, 64)
+		return err
+	},
+	"uint": func(s string) error {
+		_, err := strconv.ParseUint(s, 10, 64)
+		return err
+	},
+	"float": func(s string) error {
+		_, err := strconv.ParseFloat(s, 64)
+		return err
+	},
+	"bool": func(s string) error {
+		_, err := strconv.ParseBool(s)
+		return err
+	},
+}
+
+// cutParamType cuts the type from a path value name, as in `id@int`
+func cutParamType(name string) (string, string) {
+	name, typ, _ := strings.Cut(name, "@")
+	return name, typ
+}
+
+// plain gives a name without the types of its path values, or a
+// wildcard or exact mark, so `user.id@int.edit` is `user.id.edit`
+func plain(name string) string {
+	name = strings.TrimSuffix(name, "$")
+	name = strings.TrimSuffix(name, "...")
+	parts := strings.Split(name, ".")
+	for i := range parts {
+		parts[i], _ = cutParamType(parts[i])
+	}
+	return strings.Join(parts, ".")
+}
+
+// Params gives the names of the path values a fish is caught with,
+// in the order of its pattern
+func Params[K any](f *Fish[K]) []string {
+	return pathParams(f.pattern)
+}
+
+// ParamTypes gives the type of each path value a fish was given one
+// for in its file name
+func ParamTypes[K any](f *Fish[K]) map[string]string {
+	return f.paramTypes
+}
+
+// withParamTypes snags with 400 a request with a path value that is not
+// the type its fish was given, before any license or bait sees it
+func withParamTypes[K any](next http.Handler, f *Fish[K]) http.Handler {
+	if len(f.paramTypes) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, typ := range f.paramTypes {
+			check, known := paramTypes[typ]
+			if !known {
+				continue
+			}
+			value := r.PathValue(name)
+			if err := check(value); err != nil {
+				Snagged(w, r, http.StatusBadRequest, fmt.Errorf("%w: path value %s %q is not %s", ErrBadRequest, name, value, typ))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Param gives a path value of a request as a type. An error is given if
+// it cannot be one, which snags with 400 if given back from a lure.
+//
+//	id, err := aquatic.Param[int](r, "id")
+func Param[V ~int | ~int64 | ~uint | ~uint64 | ~float64 | ~bool | ~string](r *http.Request, name string) (V, error) {
+	var v V
+	s := r.PathValue(name)
+	if len(s) == 0 {
+		return v, fmt.Errorf("%w: missing path value %s", ErrBadRequest, name)
+	}
+
+	rv := reflect.ValueOf(&v).Elem()
+	var err error
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int64:
+		var i int64
+		i, err = strconv.ParseInt(s, 10, rv.Type().Bits())
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint64:
+		var u uint64
+		u, err = strconv.ParseUint(s, 10, rv.Type().Bits())
+		rv.SetUint(u)
+	case reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(s, 64)
+		rv.SetFloat(f)
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		rv.SetBool(b)
+	case reflect.String:
+		rv.SetString(s)
+	}
+	if err != nil {
+		var zero V
+		return zero, fmt.Errorf("%w: path value %s %q is not %s", ErrBadRequest, name, s, rv.Type())
+	}
+	return v, nil
+}
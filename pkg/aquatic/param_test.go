@@ -0,0 +1,135 @@
+package aquatic
+
+import (
+	"embed"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParam(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetPathValue("id", "42")
+	r.SetPathValue("price", "1.5")
+	r.SetPathValue("ok", "true")
+	r.SetPathValue("name", "sally")
+
+	if id, err := Param[int](r, "id"); err != nil || id != 42 {
+		t.Fatal(errUnexpectedValue, id, err)
+	}
+	if id, err := Param[uint64](r, "id"); err != nil || id != 42 {
+		t.Fatal(errUnexpectedValue, id, err)
+	}
+	if price, err := Param[float64](r, "price"); err != nil || price != 1.5 {
+		t.Fatal(errUnexpectedValue, price, err)
+	}
+	if ok, err := Param[bool](r, "ok"); err != nil || !ok {
+		t.Fatal(errUnexpectedValue, ok, err)
+	}
+	if name, err := Param[string](r, "name"); err != nil || name != "sally" {
+		t.Fatal(errUnexpectedValue, name, err)
+	}
+
+	for _, name := range []string{"name", "price", "missing"} {
+		id, err := Param[int](r, name)
+		if !errors.Is(err, ErrBadRequest) || id != 0 {
+			t.Fatal(errUnexpectedValue, name, id, err)
+		}
+	}
+}
+
+func TestParam_Typed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/ux.html":               {Data: []byte(`<a href="{{ url "user.id.edit" "id" 3 }}">edit</a>`)},
+		"ux/user.id@int.html":      {Data: []byte(`<p>user</p>`)},
+		"ux/user.id@int.edit.html": {Data: []byte(`<p>edit</p>`)},
+		"ux/price.cost@float.html": {Data: []byte(`<p>price</p>`)},
+		"ux/flag.on@bool.html":     {Data: []byte(`<p>flag</p>`)},
+		"ux/count.n@uint.html":     {Data: []byte(`<p>count</p>`)},
+		"ux/name.name.html":        {Data: []byte(`<p>name</p>`)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baited := 0
+	StockPond(&pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile(".html"): {
+			Bait: func(_ *http.Request) mockLocal {
+				baited++
+				return mockLocal{}
+			},
+		},
+	})
+	if err := Survey(&pond); err != nil {
+		t.Fatal(err)
+	}
+	mux := CastLines(&pond, false)
+
+	tests := []struct {
+		target string
+		status int
+	}{
+		{"/user/42", http.StatusOK},
+		{"/user/-7/edit", http.StatusOK},
+		{"/user/sally", http.StatusBadRequest},
+		{"/user/sally/edit", http.StatusBadRequest},
+		{"/price/1.5", http.StatusOK},
+		{"/price/cheap", http.StatusBadRequest},
+		{"/flag/true", http.StatusOK},
+		{"/flag/yes", http.StatusBadRequest},
+		{"/count/3", http.StatusOK},
+		{"/count/-3", http.StatusBadRequest},
+		{"/name/anything", http.StatusOK},
+	}
+	for _, tt := range tests {
+		baited = 0
+		w := mockCatch(mux, http.MethodGet, tt.target)
+		assertStatus(t, w, tt.status)
+		if tt.status == http.StatusBadRequest && baited > 0 {
+			t.Fatal(errUnexpectedValue, "baited", tt.target)
+		}
+	}
+
+	// known by its name without the type
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), `href="/user/3/edit"`)
+
+	for _, route := range Routes(&pond) {
+		if route.Pattern != "/user/{id}/edit" {
+			continue
+		}
+		if !slices.Equal(route.PathParams, []string{"id"}) || route.ParamTypes["id"] != "int" {
+			t.Fatal(errUnexpectedValue, route)
+		}
+	}
+}
+
+//go:embed testdata/typed
+var mockTypedFS embed.FS
+
+func TestParam_TypedFile(t *testing.T) {
+	disk, err := NewPond[mockGlobal, mockLocal]("testdata/typed", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedded, err := NewPondFS[mockGlobal, mockLocal](mockTypedFS, "testdata/typed", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pond := range []*Pond[mockGlobal, mockLocal]{&disk, &embedded} {
+		if err := Survey(pond); err != nil {
+			t.Fatal(err)
+		}
+		mux := CastLines(pond, false)
+		assertStatus(t, mockCatch(mux, http.MethodGet, "/user/42"), http.StatusOK)
+		assertStatus(t, mockCatch(mux, http.MethodGet, "/user/sally"), http.StatusBadRequest)
+	}
+}
@@ -2,14 +2,19 @@ package aquatic
 
 import (
 	"errors"
-	"fmt"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
-	"text/tabwriter"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var (
@@ -17,13 +22,31 @@ var (
 	ErrNoTemplateDir = errors.New("cannot find template directory relative to working dir")
 	// ErrInvalidExtension is given if a file is discoverd that I did not anticipate
 	ErrInvalidExtension = errors.New("invalid file extension")
+	// ErrNoFish is given if a request matches no fish in the pond
+	ErrNoFish = errors.New("no fish for this pattern")
+	// ErrMethodNotAllowed is given if a fish is requested with a method it is not caught with
+	ErrMethodNotAllowed = errors.New("method not allowed")
+	// ErrMismatchedVersion was given if a clown fish was requested with an old version.
+	//
+	// Deprecated: clown fish are caught by their fingerprinted path instead.
+	ErrMismatchedVersion = errors.New("mismatched file version requested")
 )
 
 var fishKindStr = map[int]string{
-	FishKindTuna:    "Tuna",
-	FishKindSardine: "Sardine",
-	FiskKindClown:   "Clown",
-	FiskKindAnchovy: "Anchovy",
+	FishKindTuna:       "Tuna",
+	FishKindSardine:    "Sardine",
+	FiskKindClown:      "Clown",
+	FiskKindAnchovy:    "Anchovy",
+	FishKindWhaleShark: "Whale Shark",
+	FishKindPuffer:     "Puffer",
+	FishKindBreed:      "Breed",
+}
+
+// served is true for kinds of fish that get a pattern of their own
+func served(kind int) bool {
+	return kind != FishKindMackerel &&
+		kind != FishKindWhaleShark &&
+		kind != FishKindPuffer
 }
 
 // Stock enables developer to provide what fish they think
@@ -40,17 +63,75 @@ type NewPondOptions struct {
 	// global scoped no matter where they are. Useful for an assets pond
 	// that flows into another pond.
 	GlobalSmallFish bool
+	// RawOutput opts the pond out of contextual auto-escaping. Tuna
+	// and sardines are parsed as text templates and bait is written
+	// as is. Only for ponds whose bait is trusted.
+	RawOutput bool
+	// HotReload is for development. The pond dir, and the dir of any
+	// pond flowing into it, is watched for changes. When a file changes
+	// the pond is restocked and lines are re-cast. Do not use in production.
+	HotReload bool
+	// HotReloadInterval is how often the dirs are checked for changes.
+	// Defaults to one second.
+	HotReloadInterval time.Duration
+	// HotReloadBrowser has open browsers reload the page once the pond
+	// is restocked. A reload script is added to the bobber of every tuna.
+	HotReloadBrowser bool
+	// OnSnag is called whenever a fish cannot be caught, before the
+	// puffer fish for its status is rendered. One place to log errors.
+	// Return true if the response was written and no puffer fish should be.
+	OnSnag func(w http.ResponseWriter, snag *Snag) bool
+	// Logger is what the pond tells about stocking, casting lines, and
+	// snags, with the pattern, kind, and file of the fish involved.
+	// Nothing is logged if not provided.
+	Logger *slog.Logger
+	// WakeVersions is how many previous versions of each clown and
+	// anchovy fish are still caught by their fingerprinted path after
+	// the pond is restocked on hot reload. Defaults to 3. Only versions
+	// seen by this process are kept, any other fingerprint of a fish
+	// gives it as it is now, revalidated instead of cached for good.
+	WakeVersions int
+	// PageETags gives tuna and sardine responses a strong etag of what
+	// was rendered, so a browser can revalidate a page and be told it
+	// is not modified instead of fetching it again. Pages are still
+	// rendered to know, only the response is saved.
+	PageETags bool
+	// StreamHead flushes the start of a tuna document, up to and
+	// including the bobber, before its bait is caught. So a browser
+	// fetches clown fish while slow bait is caught. The rest is
+	// streamed without an etag. A tuna with a lure, or a layout that
+	// needs bait before the bobber, is still rendered whole first.
+	StreamHead bool
+	// RenderTimeout is how long a tuna or sardine has to be caught, its
+	// action, bait, and template. Bait is given the deadline with the
+	// request context and should give up once it is done. A catch that
+	// runs out of time is given the 503 puffer fish. No timeout if zero.
+	RenderTimeout time.Duration
+	// Breeds are kinds of fish the pond does not know by itself, such
+	// as markdown or a manifest. Checked in order before the kinds it
+	// knows, so a file is found as the first breed that classifies it.
+	Breeds []Breed
 }
 
 // Pond is a collection of files from a dir with functions
 // to get a server running
 type Pond[T, K any] struct {
-	options     NewPondOptions
-	pathBase    string
+	options NewPondOptions
+
+	// fsys is where the fish of the pond are found
+	fsys fs.FS
+	// templateDir is the dir within fsys the pond is in
 	templateDir string
+	// origin is where fsys came from, if known. Prefixed to file
+	// paths so fish flowing between ponds stay unique.
+	origin string
+	// rootName is the name of the pond dir, used to find its landing tuna
+	rootName string
 
 	// 'global bait' that has been tossed into a pond for all fish to use.
 	Chum Bait[T]
+	// Lure is chum that can fail, used in place of chum if provided
+	Lure Lure[T]
 
 	// strictly for small fish to be used by tuna and sardines
 	shad map[string]*Fish[K]
@@ -60,12 +141,44 @@ type Pond[T, K any] struct {
 
 	// licenses are required for any fish to be caught
 	licenses []License
+
+	// stocks are kept so a pond can be restocked on hot reload
+	stocks []Stock[T, K]
+
+	// tributaries are ponds that flow into this one
+	tributaries []*Pond[T, K]
+
+	// mu guards fish and shad from being swapped on hot reload
+	// while they are being read
+	mu *sync.RWMutex
+
+	// chart is every pattern the pond is caught with by name, as of
+	// the latest cast. Kept so urls can be built while catching.
+	chart *atomic.Pointer[chart]
+	// buoys are the fingerprinted paths of clown and anchovy fish,
+	// as of the latest cast
+	buoys *atomic.Pointer[buoys]
+	// wake keeps previous versions of clown and anchovy fish
+	wake *wake[K]
+	// pressed is clown and anchovy fish compressed, as of the latest
+	// cast. Kept between casts so only changed fish are pressed again.
+	pressed *atomic.Pointer[pressed]
+	// drained is closed once the pond is no longer watched for hot reload
+	drained chan struct{}
+	// drain closes drained only once
+	drain *sync.Once
 }
 
 // FlowsInto can make global fish in one pond apply to another pond
 // Note that only anchovy and clown are allowed to flow (assets)
 // Useful to setup 2 ponds. one for assets, one for pages
 func FlowsInto[T, K any](p *Pond[T, K], p2 *Pond[T, K]) {
+	flow(p, p2)
+	p2.tributaries = append(p2.tributaries, p)
+}
+
+// flow copies the shad of one pond into another
+func flow[T, K any](p *Pond[T, K], p2 *Pond[T, K]) {
 	for _, f := range p.shad {
 		p2.shad[f.filePath] = f
 	}
@@ -76,25 +189,265 @@ func FlowsInto[T, K any](p *Pond[T, K], p2 *Pond[T, K]) {
 // feed then into the pond so the ponds fish inherit their stuff.
 // Regex match done against relative file path to pond base dir
 func StockPond[T, K any](p *Pond[T, K], stock Stock[T, K]) {
+	for _, unmatched := range stockFish(p, stock) {
+		p.options.Logger.Warn("did not find matching fish for regex", slog.String("regex", unmatched.String()))
+	}
+	p.stocks = append(p.stocks, stock)
+}
+
+// stockFish has the pond fish gobble the stock fish
+// they match. Gives back the regex that matched nothing.
+// A fish in a school is matched on its own only if its
+// tuna was not, since it gobbles what its tuna does.
+func stockFish[T, K any](p *Pond[T, K], stock Stock[T, K]) []*regexp.Regexp {
+	unmatched := []*regexp.Regexp{}
 	for stockFishRegex, stockFish := range stock {
 		found := false
 		for _, pondFish := range FishFinder(p) {
 			matched := stockFishRegex.Match([]byte(pondFish.scopedFilePath))
-			if !matched {
+			if matched {
+				found = true
+				Gobble(pondFish, &stockFish)
 				continue
 			}
 
-			found = true
-			Gobble(pondFish, &stockFish)
+			for i := range pondFish.school {
+				if pondFish.school[i].kind != FishKindSardine {
+					continue
+				}
+				if !stockFishRegex.Match([]byte(pondFish.school[i].scopedFilePath)) {
+					continue
+				}
+				found = true
+				Gobble(&pondFish.school[i], &stockFish)
+			}
 		}
 		if !found {
-			fmt.Println("did not find matching fish for regex: " + stockFishRegex.String())
+			unmatched = append(unmatched, stockFishRegex)
 		}
 	}
+	return unmatched
+}
+
+// gather collects a copy of every fish to be registered, sorted so
+// more explicit patterns are first. Puffers gives the puffer fish
+// for the school of a tuna, or for the shad if the school is nil.
+// Also gives back pairs of different files registered the same way,
+// where only the latter is kept. The pond must be read locked.
+func gather[T, K any](pond *Pond[T, K], puffers func(school []Fish[K]) map[int]*Fish[K]) ([]*Fish[K], [][2]*Fish[K]) {
+	// allows us to collect fish before
+	fishToRegister := make(map[string]*Fish[K])
+
+	duplicates := [][2]*Fish[K]{}
+	register := func(f *Fish[K]) {
+		key := routeKey(f)
+		if known, exists := fishToRegister[key]; exists && known.filePath != f.filePath {
+			duplicates = append(duplicates, [2]*Fish[K]{known, f})
+		}
+		fishToRegister[key] = f
+	}
+
+	globalPuffers := puffers(nil)
+	for _, child := range pond.shad {
+		if !served(child.kind) {
+			continue
+		}
+		if child.kind == FishKindTuna {
+			// unreachable
+			continue
+		}
+		// shad can flow into many ponds, so each cast
+		// gets its own copy to hook and bobber
+		c := *child
+		c.puffers = globalPuffers
+		register(&c)
+	}
+
+	// all dirs
+	for path, fishes := range pond.fish {
+		if len(fishes) == 0 {
+			pond.options.Logger.Warn("no patterns for dir", slog.String("dir", path))
+			continue
+		}
+
+		// all fish in dir
+		for _, fish := range fishes {
+			if fish.kind != FishKindTuna {
+				continue
+			}
+
+			nearest := puffers(fish.school)
+			fish.puffers = nearest
+			register(&fish)
+
+			for _, child := range fish.school {
+				if child.kind == FishKindTuna {
+					// unreachable
+					continue
+				}
+				if !served(child.kind) {
+					continue
+				}
+
+				child.puffers = nearest
+				register(&child)
+			}
+		}
+	}
+
+	sortedFish := make([]*Fish[K], 0, len(fishToRegister))
+	for _, fish := range fishToRegister {
+		if len(fish.pattern) == 0 {
+			continue
+		}
+		sortedFish = append(sortedFish, fish)
+	}
+
+	// ensure more explicit routes matched first
+	sort.Slice(sortedFish, func(i, j int) bool {
+		if sortedFish[i].pattern == sortedFish[j].pattern {
+			return routeKey(sortedFish[i]) > routeKey(sortedFish[j])
+		}
+		if c := explicitness(sortedFish[i].pattern, sortedFish[j].pattern); c != 0 {
+			return c < 0
+		}
+		return strings.Compare(sortedFish[i].pattern, sortedFish[j].pattern) > 0
+	})
+
+	return sortedFish, duplicates
+}
+
+// noPuffers is given to gather when fish are not going to be caught
+func noPuffers[K any](_ []Fish[K]) map[int]*Fish[K] {
+	return nil
+}
+
+// routeKey is what a fish is registered as, its methods and
+// pattern, so fish at the same pattern can have different methods
+func routeKey[K any](f *Fish[K]) string {
+	return strings.Join(f.Methods, ",") + " " + f.pattern
+}
+
+// castMethods gives the methods each fish is registered with. A fish
+// without methods is registered for any, unless another fish has
+// methods at the same pattern, then it is only caught with GET.
+func castMethods[K any](fishes []*Fish[K]) map[*Fish[K]][]string {
+	withMethods := map[string]bool{}
+	for _, f := range fishes {
+		if len(f.Methods) > 0 {
+			withMethods[f.pattern] = true
+		}
+	}
+
+	methods := make(map[*Fish[K]][]string, len(fishes))
+	for _, f := range fishes {
+		if len(f.Methods) > 0 {
+			methods[f] = normalMethods(f.Methods)
+			continue
+		}
+		if withMethods[f.pattern] {
+			methods[f] = []string{http.MethodGet}
+		}
+	}
+	return methods
+}
+
+// allowed gives the fish at each pattern that is only caught with some methods
+func allowed[K any](fishes []*Fish[K], methods map[*Fish[K]][]string) map[string][]*Fish[K] {
+	found := map[string][]*Fish[K]{}
+	for _, f := range fishes {
+		if len(methods[f]) == 0 {
+			continue
+		}
+		found[f.pattern] = append(found[f.pattern], f)
+	}
+	return found
+}
+
+// segmentRank is how much a segment of a pattern matches. Less is more
+// explicit, the same as the mux: a literal (or `{$}`), then a path
+// value, then a wildcard.
+func segmentRank(segment string) int {
+	if !strings.HasPrefix(segment, "{") || segment == "{$}" {
+		return 0
+	}
+	if strings.HasSuffix(segment, "...}") {
+		return 2
+	}
+	return 1
+}
+
+// explicitness compares two patterns segment by segment. Negative if
+// a is more explicit, such as `/docs/intro` to `/docs/{path...}`. A
+// longer pattern is more explicit than one it starts like.
+func explicitness(a, b string) int {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := segmentRank(as[i]) - segmentRank(bs[i]); c != 0 {
+			return c
+		}
+	}
+	return len(bs) - len(as)
+}
+
+// allowHeader gives the methods the fish at a pattern are caught with, sorted.
+// GET allows HEAD too, the same as the mux.
+func allowHeader[K any](fishes []*Fish[K], methods map[*Fish[K]][]string) []string {
+	allow := []string{}
+	for _, f := range fishes {
+		allow = append(allow, methods[f]...)
+		if slices.Contains(methods[f], http.MethodGet) {
+			allow = append(allow, http.MethodHead)
+		}
+	}
+	return normalMethods(allow)
+}
+
+// restock collects the fish of a pond again, including the ponds
+// flowing into it. Stock is fed back in before the fresh fish
+// replace the old ones.
+func restock[T, K any](p *Pond[T, K]) error {
+	fresh := Pond[T, K]{
+		options:     p.options,
+		fsys:        p.fsys,
+		templateDir: p.templateDir,
+		origin:      p.origin,
+		rootName:    p.rootName,
+		fish:        map[string][]Fish[K]{},
+		mu:          &sync.RWMutex{},
+	}
+
+	err := collect(&fresh, p.templateDir)
+	if err != nil {
+		return err
+	}
+
+	for _, tributary := range p.tributaries {
+		err := restock(tributary)
+		if err != nil {
+			return err
+		}
+		tributary.mu.RLock()
+		flow(tributary, &fresh)
+		tributary.mu.RUnlock()
+	}
+
+	for _, stock := range p.stocks {
+		stockFish(&fresh, stock)
+	}
+
+	p.mu.Lock()
+	p.fish = fresh.fish
+	p.shad = fresh.shad
+	p.mu.Unlock()
+	return nil
 }
 
 // FishFinder provides a slice of all fish
 func FishFinder[T, K any](p *Pond[T, K]) []*Fish[K] {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	all := []*Fish[K]{}
 	for _, fishes := range p.fish {
 		for i := range fishes {
@@ -104,14 +457,55 @@ func FishFinder[T, K any](p *Pond[T, K]) []*Fish[K] {
 	return all
 }
 
-// NewPond provides a new pond based on dir
+// NewPond provides a new pond based on dir, relative to the working dir
+// unless it is absolute
 func NewPond[T, K any](templateDirPath string, options NewPondOptions) (Pond[T, K], error) {
+	templateDir := templateDirPath
+	if !filepath.IsAbs(templateDir) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return Pond[T, K]{}, err
+		}
+		templateDir = filepath.Join(wd, templateDir)
+	}
+	templateDir = filepath.Clean(templateDir)
+
+	origin := filepath.ToSlash(templateDir)
+	return newPond[T, K](os.DirFS(templateDir), ".", origin, filepath.Base(templateDir), options)
+}
+
+// NewPondFS provides a new pond based on a dir within a file system.
+// Useful to embed a pond with go:embed. The name of the dir is used to
+// find the landing tuna of the pond, so a dir of "." has no root landing.
+func NewPondFS[T, K any](fsys fs.FS, dir string, options NewPondOptions) (Pond[T, K], error) {
+	dir = path.Clean(dir)
+	return newPond[T, K](fsys, dir, "", path.Base(dir), options)
+}
 
+func newPond[T, K any](fsys fs.FS, dir, origin, rootName string, options NewPondOptions) (Pond[T, K], error) {
 	p := Pond[T, K]{
-		fish:     map[string][]Fish[K]{},
-		licenses: options.Licenses,
+		fish:        map[string][]Fish[K]{},
+		licenses:    options.Licenses,
+		mu:          &sync.RWMutex{},
+		chart:       &atomic.Pointer[chart]{},
+		buoys:       &atomic.Pointer[buoys]{},
+		pressed:     &atomic.Pointer[pressed]{},
+		wake:        newWake[K](),
+		drained:     make(chan struct{}),
+		drain:       &sync.Once{},
+		fsys:        fsys,
+		templateDir: dir,
+		origin:      origin,
+		rootName:    rootName,
 	}
 
+	if options.Logger == nil {
+		// same as licenses, prefer this to checking for nil
+		options.Logger = discardLogger
+	}
+	if options.WakeVersions <= 0 {
+		options.WakeVersions = defaultWakeVersions
+	}
 	p.options = options
 
 	if p.licenses == nil {
@@ -120,20 +514,23 @@ func NewPond[T, K any](templateDirPath string, options NewPondOptions) (Pond[T,
 		p.licenses = make([]License, 0, 0)
 	}
 
-	wd, err := os.Getwd()
+	err := collect(&p, dir)
 	if err != nil {
 		return p, err
 	}
-	templateDir := filepath.Join(wd, templateDirPath)
-	templateDir = filepath.Clean(templateDir)
-	templateDir = filepath.ToSlash(templateDir)
-	p.templateDir = templateDir
+	return p, nil
+}
 
-	err = collect(&p, templateDir)
-	if err != nil {
-		return p, err
+// scope gives a path within the pond file system relative
+// to the pond dir, with a leading slash
+func scope[T, K any](p *Pond[T, K], fsPath string) string {
+	if fsPath == p.templateDir {
+		return ""
 	}
-	return p, nil
+	if p.templateDir == "." {
+		return "/" + fsPath
+	}
+	return strings.TrimPrefix(fsPath, p.templateDir)
 }
 
 // collect will gather html and css from template dir
@@ -141,9 +538,9 @@ func collect[T, K any](p *Pond[T, K], pathBase string) error {
 	if p.fish == nil {
 		p.fish = map[string][]Fish[K]{}
 	}
-	entries, err := os.ReadDir(pathBase)
+	entries, err := fs.ReadDir(p.fsys, pathBase)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
+		if errors.Is(err, fs.ErrNotExist) {
 			return ErrNoTemplateDir
 		}
 		return err
@@ -157,7 +554,7 @@ func collect[T, K any](p *Pond[T, K], pathBase string) error {
 	}
 	bigFishes := []*Fish[K]{}
 
-	dirs := []os.DirEntry{}
+	dirs := []fs.DirEntry{}
 
 	for _, e := range entries {
 		if e.IsDir() {
@@ -181,6 +578,17 @@ func collect[T, K any](p *Pond[T, K], pathBase string) error {
 		smallFishes = append(smallFishes, item)
 	}
 
+	// puffer fish are served as a page too, so
+	// they get a school the same as a tuna
+	for _, c := range smallFishes {
+		if c.kind != FishKindPuffer {
+			continue
+		}
+		for _, e := range smallFishes {
+			c.school = append(c.school, *e)
+		}
+	}
+
 	if p.shad == nil && isRoot {
 		if p.shad == nil {
 			p.shad = make(map[string]*Fish[K], len(smallFishes))
@@ -213,87 +621,151 @@ func collect[T, K any](p *Pond[T, K], pathBase string) error {
 
 	// now we can look at nested dirs
 	for _, e := range dirs {
-		collect(p, filepath.Join(pathBase, e.Name()))
+		collect(p, path.Join(pathBase, e.Name()))
 	}
 
 	return nil
 }
 
-// CastLines provides a mux to with patterns based on go templates in the specified directory
+// CastLines provides a mux to with patterns based on go templates in the specified directory.
+// If verbose every pattern registered is logged to the pond logger. Panics with
+// everything [Survey] finds wrong with the pond, same as a mux would on a conflict.
 func CastLines[T, K any](pond *Pond[T, K], verbose bool) *http.ServeMux {
-	mux := http.NewServeMux()
+	mux, err := castLines(pond, verbose)
+	if err != nil {
+		panic(err)
+	}
+	if !pond.options.HotReload {
+		return mux
+	}
+
+	// lines are re-cast as the pond changes, so the mux given
+	// back hands every request to the latest cast
+	last := soundings(pond, nil)
+	current := atomic.Pointer[http.ServeMux]{}
+	current.Store(mux)
 
-	var tw *tabwriter.Writer
+	ripples := newRipples()
+	go watch(pond, verbose, last, &current, ripples)
 
-	if verbose {
-		tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		tw.Write([]byte("kind\tpattern\tfile\n"))
+	outer := http.NewServeMux()
+	if pond.options.HotReloadBrowser {
+		outer.Handle("GET "+reloadPattern, ripples)
 	}
+	outer.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current.Load().ServeHTTP(w, r)
+	}))
+	return outer
+}
 
-	// allows us to collect fish before
-	fishToRegister := make(map[string]*Fish[K])
+// castLines provides a mux for the fish in the pond as they are right now.
+// Every cache a fish needs is warmed before a handler is given, so
+// catching a fish only ever reads from it. The pond is surveyed before
+// any handler is registered, giving back what is wrong with it.
+func castLines[T, K any](pond *Pond[T, K], verbose bool) (*http.ServeMux, error) {
+	pond.mu.RLock()
+	defer pond.mu.RUnlock()
+
+	mux := http.NewServeMux()
+	logger := pond.options.Logger
 
+	// puffer fish are found before any fish is reeled,
+	// so every fish knows the nearest one for a status
+	globalPuffers := map[int]*Fish[K]{}
 	for _, child := range pond.shad {
-		if child.kind == FishKindMackerel {
-			// not to be served
+		if child.kind != FishKindPuffer {
 			continue
 		}
-		if child.kind == FishKindTuna {
-			// unreachable
+		c := *child
+		if _, err := hook(&c, pond); err != nil {
+			logger.Error("cannot hook puffer fish", append(fishAttrs(&c), slog.Any("err", err))...)
 			continue
 		}
-		fishToRegister[child.pattern] = child
+		bobber(&c, pond)
+		status, _ := pufferStatus(c.templateName)
+		globalPuffers[status] = &c
 	}
 
-	// all dirs
-	for path, fishes := range pond.fish {
-		if len(fishes) == 0 {
-			fmt.Printf("no patterns for: %s\n", path)
-			continue
+	sortedFish, duplicates := gather(pond, func(school []Fish[K]) map[int]*Fish[K] {
+		if school == nil {
+			return globalPuffers
 		}
+		return nearestPuffers(school, globalPuffers, pond)
+	})
 
-		// all fish in dir
-		for _, fish := range fishes {
-			if fish.kind != FishKindTuna {
-				continue
-			}
+	err := survey(pond, sortedFish, duplicates)
+	if err != nil {
+		return nil, err
+	}
 
-			fishToRegister[fish.pattern] = &fish
+	charted := chartOf(sortedFish)
+	pond.chart.Store(&charted)
+	marked := buoysOf(sortedFish)
+	pond.buoys.Store(&marked)
+	squeezed := pressOf(sortedFish, pond.pressed.Load(), logger)
+	pond.pressed.Store(&squeezed)
 
-			for _, child := range fish.school {
-				if child.kind == FishKindTuna {
-					// unreachable
-					continue
-				}
+	methods := castMethods(sortedFish)
 
-				fishToRegister[child.pattern] = &child
-			}
+	// a fingerprint not known is caught as the fish it is of, whatever
+	// else would catch it, so a page cached before a deploy or restock
+	// is never given a tuna where it linked a clown fish
+	drifting := map[string]http.Handler{}
+	for _, fish := range sortedFish {
+		if fingerprinted(fish) {
+			drifting[fish.pattern] = reel(fish, pond)
 		}
 	}
 
-	sortedFish := make([]*Fish[K], 0, len(fishToRegister))
-	for pattern, fish := range fishToRegister {
-		if len(pattern) == 0 {
+	for _, fish := range sortedFish {
+		handler := adrift(reel(fish, pond), drifting)
+		fishMethods := methods[fish]
+		if verbose {
+			logger.Info("cast line", append(fishAttrs(fish), slog.Any("methods", fishMethods))...)
+		}
+		if len(fishMethods) == 0 {
+			mux.Handle(fish.pattern, handler)
 			continue
 		}
-		sortedFish = append(sortedFish, fish)
+		for _, method := range fishMethods {
+			mux.Handle(method+" "+fish.pattern, handler)
+		}
 	}
 
-	// ensure more explicit routes matched first
-	sort.Slice(sortedFish, func(i, j int) bool {
-		return strings.Compare(sortedFish[i].pattern, sortedFish[j].pattern) > 0
-	})
-
+	// clown and anchovy fish are also caught by their fingerprint, which
+	// is what bobbers link to. With hot reload previous versions are kept
+	// so a page cached before a restock can still find them.
 	for _, fish := range sortedFish {
-		if tw != nil {
-			tw.Write(fmt.Appendf(nil, "%s\t%s\t%s\n", fishKindStr[fish.kind], fish.pattern, fish.scopedFilePath))
+		if !fingerprinted(fish) {
+			continue
+		}
+		mux.Handle(fingerprint(fish.pattern, fish.hash), reel(fish, pond))
+	}
+	if pond.options.HotReload {
+		for _, s := range pond.wake.follow(sortedFish, pond.options.WakeVersions) {
+			handler := chainLicenses(handlerShell(s), licensesOf(s.fish, pond)...)
+			mux.Handle(fingerprint(s.fish.pattern, s.fish.hash), withSnag(handler, s.fish, pond))
 		}
-		mux.Handle(fish.pattern, reel(fish, pond))
 	}
 
-	if tw != nil {
-		tw.Flush()
+	// a pattern caught with some methods is given 405 for others, even
+	// if a less explicit pattern (like the root tuna) would match
+	for pattern, fish := range allowed(sortedFish, methods) {
+		allow := strings.Join(allowHeader(fish, methods), ", ")
+		mux.Handle(pattern, withSnag(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", allow)
+			Snagged(w, r, http.StatusMethodNotAllowed, ErrMethodNotAllowed)
+		}), fish[0], pond))
+	}
+
+	// with no fish at the root there is nothing to catch, so a
+	// global puffer fish for not found is given instead
+	if !slices.ContainsFunc(sortedFish, func(f *Fish[K]) bool { return f.pattern == "/" }) {
+		lost := &Fish[K]{puffers: globalPuffers}
+		mux.Handle("/", adrift(withSnag(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Snagged(w, r, http.StatusNotFound, ErrNoFish)
+		}), lost, pond), drifting))
 	}
 
-	return mux
+	return mux, nil
 }
@@ -0,0 +1,338 @@
+package aquatic
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// mockHammer catches every target from many goroutines at once,
+// giving back the status of any catch that was not ok
+func mockHammer(mux http.Handler, targets []string) []int {
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		bad []int
+	)
+
+	for range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 16 {
+				for _, target := range targets {
+					w := mockCatch(mux, http.MethodGet, target)
+					if w.Code == http.StatusOK {
+						continue
+					}
+					mu.Lock()
+					bad = append(bad, w.Code)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return bad
+}
+
+func TestCastLines_ConcurrentCatch(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{Name: "concurrent"})
+	mux := CastLines(pond, false)
+
+	style := mockFish(t, pond, "pond").school
+	targets := []string{"/", "/user", "/user/3", "/user/_card"}
+	for _, f := range style {
+		if f.kind == FiskKindClown {
+			targets = append(targets, f.pattern, fingerprint(f.pattern, f.hash))
+		}
+	}
+
+	bad := mockHammer(mux, targets)
+	if len(bad) > 0 {
+		t.Fatal(errUnexpectedStatus, bad)
+	}
+}
+
+func TestCastLines_ConcurrentCatchWhileReloading(t *testing.T) {
+	dir, rel := mockTempPond(t, map[string]string{
+		"pond.html":   "<p>{{ .Local.Name }}</p>{{ template \"_nav\" . }}",
+		"_nav.html":   "<nav></nav>",
+		"site.css":    "body{}",
+		"a/a.html":    "<p>a</p>",
+		"a/_row.html": "<tr></tr>",
+	})
+
+	pond, err := NewPond[mockGlobal, mockLocal](rel, NewPondOptions{
+		HotReload:         true,
+		HotReloadInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Drain(&pond) })
+	mux := CastLines(&pond, false)
+
+	done := make(chan error, 1)
+	go func() {
+		for i := range 20 {
+			err := mockWriteFile(filepath.Join(dir, "_nav.html"), fmt.Sprintf("<nav>%d</nav>", i))
+			if err != nil {
+				done <- err
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		done <- nil
+	}()
+
+	bad := mockHammer(mux, []string{"/", "/a", "/a/_row"})
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(bad) > 0 {
+		t.Fatal(errUnexpectedStatus, bad)
+	}
+}
+
+func TestNewPondFS_MatchesNewPond(t *testing.T) {
+	fromDir, err := NewPond[mockGlobal, mockLocal]("testdata/pond", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromFS, err := NewPondFS[mockGlobal, mockLocal](os.DirFS("testdata"), "pond", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patterns := func(p *Pond[mockGlobal, mockLocal]) []string {
+		found := []string{}
+		for _, f := range FishFinder(p) {
+			found = append(found, f.pattern+" "+f.scopedFilePath)
+			for _, c := range f.school {
+				found = append(found, c.pattern+" "+c.scopedFilePath)
+			}
+		}
+		slices.Sort(found)
+		return found
+	}
+
+	a, b := patterns(&fromDir), patterns(&fromFS)
+	if !slices.Equal(a, b) {
+		t.Fatal(errNotEqual, a, b)
+	}
+}
+
+func TestNewPondFS_Catch(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/ux.html":        {Data: []byte(`<p>{{ .Local.Name }}</p>{{ template "_nav" . }}`)},
+		"ux/_nav.html":      {Data: []byte(`<nav></nav>`)},
+		"ux/site.css":       {Data: []byte(`body{}`)},
+		"ux/user/.id.html":  {Data: []byte(`<p>user</p>`)},
+		"ux/image/fish.png": {Data: []byte("\x89PNG\r\n\x1a\n")},
+	}
+
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	StockPond(&pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("ux.html"): {
+			Bait: func(_ *http.Request) mockLocal {
+				return mockLocal{Name: "embedded"}
+			},
+		},
+	})
+	mux := CastLines(&pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), "<p>embedded</p><nav></nav>")
+	assertContains(t, w.Body.String(), "/site."+hashOf([]byte("body{}"))+".css")
+
+	w = mockCatch(mux, http.MethodGet, "/site."+hashOf([]byte("body{}"))+".css")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), "body{}")
+
+	w = mockCatch(mux, http.MethodGet, "/user/1")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), "<p>user</p>")
+
+	w = mockCatch(mux, http.MethodGet, "/image/fish.png")
+	assertStatus(t, w, http.StatusOK)
+}
+
+func TestNewPondFS_NoDir(t *testing.T) {
+	_, err := NewPondFS[mockGlobal, mockLocal](fstest.MapFS{}, "missing", NewPondOptions{})
+	if !errors.Is(err, ErrNoTemplateDir) {
+		t.Fatal(err)
+	}
+}
+
+func TestCastLines_Methods(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/ux.html":                {Data: []byte(`<p>home</p>`)},
+		"ux/user.id+post.html":      {Data: []byte(`<p>saved {{ .Local.Name }}</p>`)},
+		"ux/table/table.html":       {Data: []byte(`{{ template "_row" . }}`)},
+		"ux/table/_row.html":        {Data: []byte(`<tr>get</tr>`)},
+		"ux/table/_row+post.html":   {Data: []byte(`<tr>post</tr>`)},
+		"ux/table/_row+DELETE.html": {Data: []byte(`<tr>delete</tr>`)},
+		"ux/table/_stateful.html":   {Data: []byte(`<table></table>`)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	StockPond(&pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("_stateful"): {
+			Methods: []string{"post", "get"},
+		},
+		regexp.MustCompile(`user.id\+post`): {
+			Bait: func(r *http.Request) mockLocal {
+				return mockLocal{Name: r.PathValue("id")}
+			},
+		},
+	})
+	mux := CastLines(&pond, false)
+
+	tests := []struct {
+		method, target string
+		status         int
+		contains       string
+	}{
+		{http.MethodGet, "/table/_row", http.StatusOK, "<tr>get</tr>"},
+		{http.MethodHead, "/table/_row", http.StatusOK, ""},
+		{http.MethodPost, "/table/_row", http.StatusOK, "<tr>post</tr>"},
+		{http.MethodDelete, "/table/_row", http.StatusOK, "<tr>delete</tr>"},
+		{http.MethodPut, "/table/_row", http.StatusMethodNotAllowed, ""},
+		{http.MethodPost, "/table/_stateful", http.StatusOK, "<table></table>"},
+		{http.MethodPatch, "/table/_stateful", http.StatusMethodNotAllowed, ""},
+		{http.MethodPost, "/user/3", http.StatusOK, "<p>saved 3</p>"},
+		{http.MethodGet, "/user/3", http.StatusMethodNotAllowed, ""},
+		{http.MethodDelete, "/table", http.StatusOK, "<tr>get</tr>"},
+	}
+	for _, tt := range tests {
+		w := mockCatch(mux, tt.method, tt.target)
+		if w.Code != tt.status {
+			t.Fatal(errUnexpectedStatus, tt.method, tt.target, w.Code)
+		}
+		assertContains(t, w.Body.String(), tt.contains)
+	}
+
+	allows := map[string]string{
+		"/table/_row":      "DELETE, GET, HEAD, POST",
+		"/table/_stateful": "GET, HEAD, POST",
+		"/user/3":          "POST",
+	}
+	for target, allow := range allows {
+		w := mockCatch(mux, http.MethodPut, target)
+		if w.Header().Get("Allow") != allow {
+			t.Fatal(errUnexpectedValue, target, w.Header().Get("Allow"))
+		}
+	}
+}
+
+func TestCutMethod(t *testing.T) {
+	tests := map[string][2]string{
+		"_row+post":     {"_row", http.MethodPost},
+		"user.id+PATCH": {"user.id", http.MethodPatch},
+		"user.id":       {"user.id", ""},
+		"post":          {"post", ""},
+		"_row+posts":    {"_row+posts", ""},
+		"blog.post":     {"blog.post", ""},
+		".id.delete":    {".id.delete", ""},
+	}
+	for name, expected := range tests {
+		cut, method, _ := cutMethod(name)
+		if cut != expected[0] || method != expected[1] {
+			t.Fatal(errUnexpectedValue, name, cut, method)
+		}
+	}
+}
+
+func TestCastLines_WildcardAndExact(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/ux$.html":           {Data: []byte(`<p>home</p>`)},
+		"ux/docs/docs.html":     {Data: []byte(`<p>docs</p>`)},
+		"ux/docs/intro.html":    {Data: []byte(`<p>intro</p>`)},
+		"ux/docs/.page.html":    {Data: []byte(`<p>page {{ .Local.Name }}</p>`)},
+		"ux/docs/.path....html": {Data: []byte(`<p>path {{ .Local.Name }}</p>`)},
+		"ux/file.name....html":  {Data: []byte(`<a href="{{ url "file.name" "name" "a/b c" }}">file</a>`)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	StockPond(&pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile(`\.(page|path)`): {
+			Bait: func(r *http.Request) mockLocal {
+				return mockLocal{Name: r.PathValue("page") + r.PathValue("path")}
+			},
+		},
+	})
+	if err := Survey(&pond); err != nil {
+		t.Fatal(err)
+	}
+	mux := CastLines(&pond, false)
+
+	// literal, then path value, then wildcard
+	tests := []struct {
+		target   string
+		status   int
+		contains string
+	}{
+		{"/", http.StatusOK, "<p>home</p>"},
+		{"/elsewhere", http.StatusNotFound, ""},
+		{"/docs", http.StatusOK, "<p>docs</p>"},
+		{"/docs/intro", http.StatusOK, "<p>intro</p>"},
+		{"/docs/setup", http.StatusOK, "<p>page setup</p>"},
+		{"/docs/setup/linux", http.StatusOK, "<p>path setup/linux</p>"},
+		{"/docs/a/b/c", http.StatusOK, "<p>path a/b/c</p>"},
+		{"/file/x", http.StatusOK, `href="/file/a/b%20c"`},
+	}
+	for _, tt := range tests {
+		w := mockCatch(mux, http.MethodGet, tt.target)
+		if w.Code != tt.status {
+			t.Fatal(errUnexpectedStatus, tt.target, w.Code)
+		}
+		assertContains(t, w.Body.String(), tt.contains)
+	}
+
+	patterns := []string{}
+	for _, route := range Routes(&pond) {
+		if strings.HasPrefix(route.Pattern, "/docs") {
+			patterns = append(patterns, route.Pattern)
+		}
+	}
+	expected := []string{"/docs/intro", "/docs/{page}", "/docs/{path...}", "/docs"}
+	if !slices.Equal(patterns, expected) {
+		t.Fatal(errNotEqual, patterns)
+	}
+}
+
+func TestExplicitness(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"/docs/intro", "/docs/{page}"},
+		{"/docs/{page}", "/docs/{path...}"},
+		{"/docs/intro/more", "/docs/{path...}"},
+		{"/user/{id}/edit", "/user/{id}"},
+		{"/{$}", "/{page}"},
+	}
+	for _, tt := range tests {
+		if explicitness(tt.a, tt.b) >= 0 || explicitness(tt.b, tt.a) <= 0 {
+			t.Fatal(errUnexpectedValue, tt.a, tt.b)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package aquatic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// minPressSize is how big a page must be to be worth compressing
+const minPressSize = 1024
+
+// pressings are the encodings a pond can give, most preferred first,
+// with the extension of a precompressed sibling file for each.
+// Only gzip is pressed by the pond, brotli only if precompressed.
+var pressings = []struct {
+	encoding string
+	ext      string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// pressed is the compressed content of clown and anchovy
+// fish by their hash, then encoding
+type pressed map[string]map[string][]byte
+
+// compressible tells if content of a mime gets smaller compressed.
+// Images, fonts like woff2, audio, and video already are.
+func compressible(mime string) bool {
+	mime, _, _ = strings.Cut(mime, ";")
+	return strings.HasPrefix(mime, "text/") ||
+		strings.HasSuffix(mime, "+xml") ||
+		strings.HasSuffix(mime, "+json") ||
+		strings.HasSuffix(mime, "/javascript") ||
+		strings.HasSuffix(mime, "/json") ||
+		strings.HasSuffix(mime, "/xml")
+}
+
+// pressOf compresses every compressible clown and anchovy fish lines are
+// cast for. Those known from before by hash are not pressed again.
+func pressOf[K any](fishes []*Fish[K], before *pressed, logger *slog.Logger) pressed {
+	p := pressed{}
+	for _, f := range fishes {
+		if f.kind != FiskKindClown && f.kind != FiskKindAnchovy {
+			continue
+		}
+		if !compressible(f.mime) {
+			continue
+		}
+		if _, exists := p[f.hash]; !exists && before != nil {
+			if known, exists := (*before)[f.hash]; exists {
+				p[f.hash] = known
+				continue
+			}
+		}
+
+		// fish of the same content share what is pressed, so a
+		// sibling of one is good for all. Copied since it may be
+		// read while catching.
+		encoded := maps.Clone(p[f.hash])
+		if encoded == nil {
+			encoded = map[string][]byte{}
+		}
+		for _, pressing := range pressings {
+			if _, exists := encoded[pressing.encoding]; exists {
+				continue
+			}
+			b, ok := siblingOf(f, pressing.encoding, pressing.ext, logger)
+			if ok {
+				encoded[pressing.encoding] = b
+			}
+		}
+		if _, exists := encoded["gzip"]; !exists {
+			b, err := fs.ReadFile(f.fsys, f.fsPath)
+			if err == nil {
+				encoded["gzip"] = gzipped(b, gzip.BestCompression)
+			}
+		}
+		p[f.hash] = encoded
+	}
+	return p
+}
+
+// siblingOf reads the precompressed sibling of a fish for an encoding.
+// It is sent with the hash of the fish, so one not made again after the
+// fish changed is left. Gzip is checked by what it decompresses to, and
+// brotli by being no older than the fish, if the file system knows.
+func siblingOf[K any](f *Fish[K], encoding, ext string, logger *slog.Logger) ([]byte, bool) {
+	name := f.fsPath + ext
+	b, err := fs.ReadFile(f.fsys, name)
+	if err != nil {
+		return nil, false
+	}
+
+	stale := false
+	if encoding == "gzip" {
+		plain := []byte(nil)
+		zr, err := gzip.NewReader(bytes.NewReader(b))
+		if err == nil {
+			plain, err = io.ReadAll(zr)
+		}
+		stale = err != nil || hashOf(plain) != f.hash
+	} else {
+		info, err := fs.Stat(f.fsys, name)
+		stale = err != nil || (!f.modTime.IsZero() && info.ModTime().Before(f.modTime))
+	}
+	if stale {
+		logger.Warn("skipped stale precompressed sibling", append(fishAttrs(f), slog.String("sibling", name))...)
+		return nil, false
+	}
+	return b, true
+}
+
+// accepts tells if a request accepts an encoding, by its Accept-Encoding
+func accepts(r *http.Request, encoding string) bool {
+	wildcard := false
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.TrimSpace(name)
+		if name != encoding && name != "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if found && strings.TrimSpace(key) == "q" {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		if name == encoding {
+			return q > 0
+		}
+		wildcard = q > 0
+	}
+	return wildcard
+}
+
+// pick chooses the most preferred encoding of a fish a request accepts.
+// Gives no encoding if none.
+func pick[T, K any](r *http.Request, f *Fish[K], pond *Pond[T, K]) (string, []byte) {
+	p := pond.pressed.Load()
+	if p == nil {
+		return "", nil
+	}
+	encoded := (*p)[f.hash]
+	for _, pressing := range pressings {
+		b, exists := encoded[pressing.encoding]
+		if exists && accepts(r, pressing.encoding) {
+			return pressing.encoding, b
+		}
+	}
+	return "", nil
+}
+
+var gzipWriters = sync.Pool{
+	New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	},
+}
+
+// gzipped compresses bytes with gzip
+func gzipped(b []byte, level int) []byte {
+	buff := bytes.NewBuffer(make([]byte, 0, len(b)/3))
+
+	var zw *gzip.Writer
+	if level == gzip.DefaultCompression {
+		zw = gzipWriters.Get().(*gzip.Writer)
+		defer gzipWriters.Put(zw)
+		zw.Reset(buff)
+	} else {
+		zw, _ = gzip.NewWriterLevel(buff, level)
+	}
+
+	// writing to a buffer cannot fail
+	zw.Write(b)
+	zw.Close()
+	return buff.Bytes()
+}
+
+// pressPage compresses a rendered tuna or sardine if the request accepts
+// gzip and it is worth it. Gives back the bytes to write.
+func pressPage(w http.ResponseWriter, r *http.Request, b []byte) []byte {
+	w.Header().Add("Vary", "Accept-Encoding")
+	if len(b) < minPressSize || !accepts(r, "gzip") {
+		return b
+	}
+	w.Header().Set("Content-Encoding", "gzip")
+	return gzipped(b, gzip.DefaultCompression)
+}
@@ -0,0 +1,313 @@
+package aquatic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// mockUnseekableFS hides that its files can seek
+type mockUnseekableFS struct {
+	fs.FS
+}
+
+type mockUnseekableFile struct {
+	fs.File
+}
+
+func (m mockUnseekableFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(m.FS, name)
+}
+
+func (m mockUnseekableFS) Open(name string) (fs.File, error) {
+	file, err := m.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return mockUnseekableFile{file}, nil
+}
+
+func mockUngzip(t *testing.T, b []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(plain)
+}
+
+func TestAccepts(t *testing.T) {
+	tests := []struct {
+		header   string
+		encoding string
+		expected bool
+	}{
+		{"gzip, deflate, br", "gzip", true},
+		{"gzip, deflate, br", "br", true},
+		{"deflate", "gzip", false},
+		{"", "gzip", false},
+		{"gzip;q=0", "gzip", false},
+		{"gzip ; q=0.5", "gzip", true},
+		{"*", "br", true},
+		{"*, br;q=0", "br", false},
+		{"br;q=0, *", "br", false},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", tt.header)
+		if accepts(r, tt.encoding) != tt.expected {
+			t.Fatal(errUnexpectedValue, tt.header, tt.encoding)
+		}
+	}
+}
+
+func TestCompressible(t *testing.T) {
+	tests := map[string]bool{
+		"text/css; charset=utf-8":        true,
+		"text/javascript; charset=utf-8": true,
+		"image/svg+xml":                  true,
+		"application/json":               true,
+		"image/png":                      false,
+		"font/woff2":                     false,
+		"video/mp4":                      false,
+	}
+	for mime, expected := range tests {
+		if compressible(mime) != expected {
+			t.Fatal(errUnexpectedValue, mime)
+		}
+	}
+}
+
+func TestPress_Asset(t *testing.T) {
+	css := strings.Repeat("body { margin: 0; }\n", 100)
+	fancy := css + "/* fancy */"
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 100)
+	fsys := fstest.MapFS{
+		"ux/ux.html":            {Data: []byte(`<p>home</p>`)},
+		"ux/site.css":           {Data: []byte(css)},
+		"ux/fancy.css":          {Data: []byte(fancy)},
+		"ux/fancy.css.br":       {Data: []byte("pretend brotli")},
+		"ux/image/smile.png":    {Data: []byte(png)},
+		"ux/image/smile.png.gz": {Data: []byte("never sent")},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{GlobalSmallFish: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := CastLines(&pond, false)
+
+	accept := map[string]string{"Accept-Encoding": "gzip, br"}
+
+	w := mockRevalidate(mux, http.MethodGet, "/site.css", accept)
+	assertStatus(t, w, http.StatusOK)
+	if w.Header().Get("Content-Encoding") != "gzip" ||
+		!strings.Contains(w.Header().Get("Vary"), "Accept-Encoding") ||
+		w.Header().Get("ETag") != etagOf(hashOf([]byte(css))+"-gzip") {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+	if mockUngzip(t, w.Body.Bytes()) != css {
+		t.Fatal(errUnexpectedValue, "gzip")
+	}
+
+	// revalidated by the etag of its encoding
+	w = mockRevalidate(mux, http.MethodGet, "/site.css", map[string]string{
+		"Accept-Encoding": "gzip",
+		"If-None-Match":   etagOf(hashOf([]byte(css)) + "-gzip"),
+	})
+	assertStatus(t, w, http.StatusNotModified)
+
+	w = mockCatch(mux, http.MethodGet, "/site.css")
+	assertStatus(t, w, http.StatusOK)
+	if len(w.Header().Get("Content-Encoding")) > 0 || w.Body.String() != css {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+
+	// precompressed siblings are preferred
+	w = mockRevalidate(mux, http.MethodGet, "/fancy.css", accept)
+	assertStatus(t, w, http.StatusOK)
+	if w.Header().Get("Content-Encoding") != "br" || w.Body.String() != "pretend brotli" {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+	w = mockRevalidate(mux, http.MethodGet, "/fancy.css", map[string]string{"Accept-Encoding": "gzip"})
+	if w.Header().Get("Content-Encoding") != "gzip" || mockUngzip(t, w.Body.Bytes()) != fancy {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+
+	// already compressed
+	w = mockRevalidate(mux, http.MethodGet, "/image/smile.png", accept)
+	assertStatus(t, w, http.StatusOK)
+	if len(w.Header().Get("Content-Encoding")) > 0 || len(w.Header().Get("Vary")) > 0 || w.Body.String() != png {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+}
+
+func TestPress_StaleSibling(t *testing.T) {
+	css := strings.Repeat("body { margin: 0; }\n", 100)
+	edited := css + "/* edited */"
+	made := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fresh := gzipped([]byte(css), gzip.HuffmanOnly)
+	fsys := fstest.MapFS{
+		"ux/ux.html":       {Data: []byte(`<p>home</p>`)},
+		"ux/site.css":      {Data: []byte(css), ModTime: made},
+		"ux/site.css.gz":   {Data: fresh, ModTime: made},
+		"ux/edited.css":    {Data: []byte(edited), ModTime: made.Add(time.Hour)},
+		"ux/edited.css.gz": {Data: gzipped([]byte(css), gzip.BestSpeed), ModTime: made},
+		"ux/edited.css.br": {Data: []byte("pretend brotli"), ModTime: made},
+		"ux/broken.css":    {Data: []byte(css + "/* broken */")},
+		"ux/broken.css.gz": {Data: []byte("not gzip")},
+	}
+	logs := &bytes.Buffer{}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{
+		Logger: slog.New(slog.NewTextHandler(logs, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := CastLines(&pond, false)
+
+	accept := map[string]string{"Accept-Encoding": "gzip, br"}
+
+	// still made from the file
+	w := mockRevalidate(mux, http.MethodGet, "/site.css", accept)
+	if w.Header().Get("Content-Encoding") != "gzip" || !bytes.Equal(w.Body.Bytes(), fresh) {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+
+	// pressed by the pond instead
+	for target, content := range map[string]string{"/edited.css": edited, "/broken.css": css + "/* broken */"} {
+		w := mockRevalidate(mux, http.MethodGet, target, accept)
+		if w.Header().Get("Content-Encoding") != "gzip" || mockUngzip(t, w.Body.Bytes()) != content {
+			t.Fatal(errUnexpectedValue, target, w.Header())
+		}
+	}
+
+	for _, sibling := range []string{"edited.css.gz", "edited.css.br", "broken.css.gz"} {
+		assertContains(t, logs.String(), sibling)
+	}
+	assertNotContains(t, logs.String(), "site.css.gz")
+}
+
+func TestPress_Page(t *testing.T) {
+	long := strings.Repeat("<p>fish</p>", 200)
+	fsys := fstest.MapFS{
+		"ux/ux.html":          {Data: []byte(long)},
+		"ux/short/short.html": {Data: []byte(`<p>short</p>`)},
+		"ux/_row.html":        {Data: []byte(long)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{PageETags: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := CastLines(&pond, false)
+
+	accept := map[string]string{"Accept-Encoding": "gzip"}
+	for _, target := range []string{"/", "/_row"} {
+		w := mockRevalidate(mux, http.MethodGet, target, accept)
+		assertStatus(t, w, http.StatusOK)
+		if w.Header().Get("Content-Encoding") != "gzip" || !strings.Contains(mockUngzip(t, w.Body.Bytes()), long) {
+			t.Fatal(errUnexpectedValue, target, w.Header())
+		}
+		if w.Header().Get("ETag") != etagOf(hashOf(w.Body.Bytes())) {
+			t.Fatal(errUnexpectedValue, target, w.Header())
+		}
+		identity := mockCatch(mux, http.MethodGet, target)
+		if identity.Header().Get("ETag") == w.Header().Get("ETag") {
+			t.Fatal(errUnexpectedValue, "same etag for both encodings")
+		}
+	}
+
+	// not worth it
+	w := mockRevalidate(mux, http.MethodGet, "/short", accept)
+	assertStatus(t, w, http.StatusOK)
+	if len(w.Header().Get("Content-Encoding")) > 0 || !slices.Contains(w.Header().Values("Vary"), "Accept-Encoding") {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+}
+
+func TestStream_Range(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("0123456789", 100)
+	files := map[string]string{
+		"pond.html": "<p>pond</p>",
+		"smile.png": png,
+	}
+
+	_, rel := mockTempPond(t, files)
+	disk, err := NewPond[mockGlobal, mockLocal](rel, NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapFS := fstest.MapFS{}
+	for name, content := range files {
+		mapFS["ux/"+name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	embedded, err := NewPondFS[mockGlobal, mockLocal](mapFS, "ux", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	etag := etagOf(hashOf([]byte(png)))
+	for _, pond := range []*Pond[mockGlobal, mockLocal]{&disk, &embedded} {
+		mux := CastLines(pond, false)
+
+		w := mockCatch(mux, http.MethodGet, "/smile.png")
+		assertStatus(t, w, http.StatusOK)
+		if w.Header().Get("Accept-Ranges") != "bytes" || w.Body.String() != png {
+			t.Fatal(errUnexpectedValue, w.Header())
+		}
+
+		w = mockRevalidate(mux, http.MethodGet, "/smile.png", map[string]string{"Range": "bytes=18-27"})
+		assertStatus(t, w, http.StatusPartialContent)
+		if w.Body.String() != "0123456789" || w.Header().Get("Content-Range") != "bytes 18-27/1008" {
+			t.Fatal(errUnexpectedValue, w.Header(), w.Body.String())
+		}
+
+		// a range of the same version only
+		w = mockRevalidate(mux, http.MethodGet, "/smile.png", map[string]string{"Range": "bytes=18-27", "If-Range": etag})
+		assertStatus(t, w, http.StatusPartialContent)
+		w = mockRevalidate(mux, http.MethodGet, "/smile.png", map[string]string{"Range": "bytes=18-27", "If-Range": `"old"`})
+		assertStatus(t, w, http.StatusOK)
+		if w.Body.String() != png {
+			t.Fatal(errUnexpectedValue, "full body")
+		}
+
+		w = mockRevalidate(mux, http.MethodGet, "/smile.png", map[string]string{"Range": "bytes=5000-"})
+		assertStatus(t, w, http.StatusRequestedRangeNotSatisfiable)
+	}
+}
+
+func TestStream_Unseekable(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("0123456789", 100)
+	fsys := mockUnseekableFS{fstest.MapFS{
+		"ux/ux.html":   {Data: []byte(`<p>home</p>`)},
+		"ux/smile.png": {Data: []byte(png)},
+	}}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := CastLines(&pond, false)
+
+	w := mockRevalidate(mux, http.MethodGet, "/smile.png", map[string]string{"Range": "bytes=18-27"})
+	assertStatus(t, w, http.StatusOK)
+	if w.Header().Get("Accept-Ranges") != "none" || w.Body.String() != png || w.Header().Get("Content-Length") != "1008" {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+
+	w = mockCatch(mux, http.MethodHead, "/smile.png")
+	assertStatus(t, w, http.StatusOK)
+	if w.Body.Len() > 0 {
+		t.Fatal(errUnexpectedValue, "head body")
+	}
+}
@@ -0,0 +1,171 @@
+package aquatic
+
+import (
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net/http"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// reloadPattern is where browsers listen for a pond to be restocked
+	reloadPattern = "/_gofish/reload"
+	// reloadScript is given to the bobber so browsers reload once restocked
+	reloadScript = `<script>new EventSource("` + reloadPattern + `").onmessage = () => location.reload()</script>`
+	// defaultHotReloadInterval is how often a pond is checked if not specified
+	defaultHotReloadInterval = time.Second
+)
+
+// sounding is what a file looked like last time the pond was checked
+type sounding struct {
+	size    int64
+	modTime int64
+}
+
+// soundings walks the dirs of a pond, and the ponds flowing into it,
+// noting what every file looks like. Any change means a restock.
+func soundings[T, K any](p *Pond[T, K], found map[string]sounding) map[string]sounding {
+	if found == nil {
+		found = map[string]sounding{}
+	}
+
+	fs.WalkDir(p.fsys, p.templateDir, func(fsPath string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		found[path.Join(p.origin, fsPath)] = sounding{
+			size:    info.Size(),
+			modTime: info.ModTime().UnixNano(),
+		}
+		return nil
+	})
+
+	for _, tributary := range p.tributaries {
+		soundings(tributary, found)
+	}
+
+	return found
+}
+
+// watch checks the pond for changes until it is drained. On change the
+// pond is restocked, lines are re-cast, and the browsers are told.
+// Last is the soundings taken when the lines were first cast.
+func watch[T, K any](p *Pond[T, K], verbose bool, last map[string]sounding, current *atomic.Pointer[http.ServeMux], r *ripples) {
+	interval := p.options.HotReloadInterval
+	if interval <= 0 {
+		interval = defaultHotReloadInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// a change is only seen once lines are cast with it, so a file
+	// read mid edit is tried again. Failing is only told once a change.
+	var failed map[string]sounding
+	for {
+		select {
+		case <-p.drained:
+			return
+		case <-ticker.C:
+		}
+
+		now := soundings(p, nil)
+		if maps.Equal(last, now) {
+			continue
+		}
+		quiet := maps.Equal(failed, now)
+		failed = now
+
+		err := restock(p)
+		if err != nil {
+			if !quiet {
+				p.options.Logger.Error("cannot restock pond", slog.String("dir", p.origin), slog.Any("err", err))
+			}
+			continue
+		}
+
+		mux, err := castLines(p, verbose)
+		if err != nil {
+			// keep catching with the lines already cast until fixed
+			if !quiet {
+				p.options.Logger.Error("cannot cast lines", slog.String("dir", p.origin), slog.Any("err", err))
+			}
+			continue
+		}
+		p.options.Logger.Info("restocked pond", slog.String("dir", p.origin))
+		last, failed = now, nil
+		current.Store(mux)
+		r.spread()
+	}
+}
+
+// Drain stops watching a pond for hot reload. The lines already cast
+// keep catching with the pond as it was. Safe to call more than once.
+func Drain[T, K any](p *Pond[T, K]) {
+	p.drain.Do(func() {
+		close(p.drained)
+	})
+}
+
+// ripples spread out to every browser listening when a pond changes
+type ripples struct {
+	mu        sync.Mutex
+	listeners map[chan struct{}]bool
+}
+
+func newRipples() *ripples {
+	return &ripples{
+		listeners: map[chan struct{}]bool{},
+	}
+}
+
+// spread tells every listening browser to reload
+func (r *ripples) spread() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for listener := range r.listeners {
+		select {
+		case listener <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// ServeHTTP holds open an event stream until the pond changes
+func (r *ripples) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	listener := make(chan struct{}, 1)
+	r.mu.Lock()
+	r.listeners[listener] = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.listeners, listener)
+		r.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	select {
+	case <-req.Context().Done():
+	case <-listener:
+		w.Write([]byte("data: reload\n\n"))
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,185 @@
+package aquatic
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// mockTempPond makes a pond dir in a temp dir with the files
+// given, giving back its path relative to the working dir
+func mockTempPond(t *testing.T, files map[string]string) (string, string) {
+	dir := filepath.Join(t.TempDir(), "pond")
+	for name, content := range files {
+		mockWrite(t, filepath.Join(dir, name), content)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel, err := filepath.Rel(wd, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, rel
+}
+
+func mockWrite(t *testing.T, path, content string) {
+	err := mockWriteFile(path, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// mockWriteFile writes a pond file without a test, so it
+// can be used from a goroutine that must not call t.Fatal
+func mockWriteFile(path, content string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+	// written aside then moved in, so a reload never sees it half written
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, []byte(content), 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// mockEventually retries a catch until the body has what is expected
+func mockEventually(t *testing.T, mux http.Handler, target string, status int, s string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		w := mockCatch(mux, http.MethodGet, target)
+		if w.Code == status && strings.Contains(w.Body.String(), s) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Log(w.Code, w.Body.String())
+			t.Fatal(errMissingContent, s)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHotReload(t *testing.T) {
+	dir, rel := mockTempPond(t, map[string]string{
+		"pond.html": "<p>first</p>",
+		"site.css":  "body{}",
+	})
+
+	pond, err := NewPond[mockGlobal, mockLocal](rel, NewPondOptions{
+		HotReload:         true,
+		HotReloadInterval: 10 * time.Millisecond,
+		HotReloadBrowser:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Drain(&pond) })
+	mux := CastLines(&pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), "<p>first</p>")
+	assertContains(t, w.Body.String(), reloadScript)
+
+	mockWrite(t, filepath.Join(dir, "pond.html"), "<p>second</p>")
+	mockEventually(t, mux, "/", http.StatusOK, "<p>second</p>")
+
+	mockWrite(t, filepath.Join(dir, "about.html"), "<p>about</p>")
+	mockEventually(t, mux, "/about", http.StatusOK, "<p>about</p>")
+
+	mockWrite(t, filepath.Join(dir, "site.css"), "body{margin:0}")
+	mockEventually(t, mux, "/", http.StatusOK, hashOf([]byte("body{margin:0}")))
+
+	err = os.Remove(filepath.Join(dir, "about.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockEventually(t, mux, "/about", http.StatusOK, "<p>second</p>")
+}
+
+// mockFlakyFS is a file system that can fail to open a file
+// without anything about it changing, like a file mid edit
+type mockFlakyFS struct {
+	mu     sync.Mutex
+	files  fstest.MapFS
+	broken string
+}
+
+func (m *mockFlakyFS) Open(name string) (fs.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if name == m.broken {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return m.files.Open(name)
+}
+
+func (m *mockFlakyFS) set(name, content string, broken bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(content) > 0 {
+		m.files[name] = &fstest.MapFile{Data: []byte(content), ModTime: time.Now()}
+	}
+	m.broken = ""
+	if broken {
+		m.broken = name
+	}
+}
+
+func TestHotReload_RetriesFailedRestock(t *testing.T) {
+	fsys := &mockFlakyFS{files: fstest.MapFS{
+		"ux/ux.html": {Data: []byte("<p>first</p>")},
+	}}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{
+		HotReload:         true,
+		HotReloadInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Drain(&pond) })
+	mux := CastLines(&pond, false)
+
+	fsys.set("ux/ux.html", "<p>second</p>", true)
+	time.Sleep(100 * time.Millisecond)
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertContains(t, w.Body.String(), "<p>first</p>")
+
+	// nothing about the file changes, it can just be read now
+	fsys.set("ux/ux.html", "", false)
+	mockEventually(t, mux, "/", http.StatusOK, "<p>second</p>")
+}
+
+func TestHotReload_Drain(t *testing.T) {
+	dir, rel := mockTempPond(t, map[string]string{
+		"pond.html": "<p>first</p>",
+	})
+	pond, err := NewPond[mockGlobal, mockLocal](rel, NewPondOptions{
+		HotReload:         true,
+		HotReloadInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := CastLines(&pond, false)
+
+	Drain(&pond)
+	Drain(&pond)
+	mockWrite(t, filepath.Join(dir, "pond.html"), "<p>second</p>")
+	time.Sleep(100 * time.Millisecond)
+
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), "<p>first</p>")
+}
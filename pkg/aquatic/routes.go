@@ -0,0 +1,74 @@
+package aquatic
+
+import (
+	"strings"
+)
+
+// Route is what a fish is caught with, as given by [Routes]
+type Route struct {
+	// Pattern is what the mux matches, without methods
+	Pattern string `json:"pattern"`
+	// Methods the fish is caught with. Any method if empty.
+	Methods []string `json:"methods,omitempty"`
+	// Kind of fish, such as Tuna
+	Kind string `json:"kind"`
+	// File is the path of the fish relative to its pond
+	File string `json:"file"`
+	// TemplateName is the name a fish is known by in templates
+	TemplateName string `json:"templateName,omitempty"`
+	// PathParams are the names of the path values in the pattern
+	PathParams []string `json:"pathParams"`
+	// ParamTypes are the types path values were given in the file name
+	ParamTypes map[string]string `json:"paramTypes,omitempty"`
+	// Licenses is how many licenses, pond and fish, must be met to catch it
+	Licenses int `json:"licenses"`
+	// Hash of the content of the fish
+	Hash string `json:"hash"`
+}
+
+// Routes gives every route the pond would cast lines for, in the order
+// they are registered. Useful to see what a pond serves without serving it.
+func Routes[T, K any](pond *Pond[T, K]) []Route {
+	pond.mu.RLock()
+	defer pond.mu.RUnlock()
+
+	sortedFish, _ := gather(pond, noPuffers[K])
+	methods := castMethods(sortedFish)
+
+	routes := make([]Route, 0, len(sortedFish))
+	for _, f := range sortedFish {
+		templateName := ""
+		if f.kind == FishKindTuna || f.kind == FishKindSardine {
+			templateName = f.templateName
+		}
+		routes = append(routes, Route{
+			Pattern:      f.pattern,
+			Methods:      methods[f],
+			Kind:         kindName(f),
+			File:         f.scopedFilePath,
+			TemplateName: templateName,
+			PathParams:   pathParams(f.pattern),
+			ParamTypes:   f.paramTypes,
+			Licenses:     len(pond.licenses) + len(f.Licenses),
+			Hash:         f.hash,
+		})
+	}
+	return routes
+}
+
+// pathParams gives the names of the path values in a pattern,
+// such as `id` for `/user/{id}`. The `{$}` end marker is not one.
+func pathParams(pattern string) []string {
+	params := []string{}
+	for _, segment := range strings.Split(pattern, "/") {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		name := strings.TrimSuffix(segment[1:len(segment)-1], "...")
+		if name == "$" {
+			continue
+		}
+		params = append(params, name)
+	}
+	return params
+}
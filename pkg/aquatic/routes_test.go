@@ -0,0 +1,100 @@
+package aquatic
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRoutes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/ux.html":              {Data: []byte(`<p>home</p>`)},
+		"ux/site.css":             {Data: []byte(`body{}`)},
+		"ux/_layout.html":         {Data: []byte(`{{ .Body }}`)},
+		"ux/user/user.html":       {Data: []byte(`<p>users</p>`)},
+		"ux/user/.id.edit.html":   {Data: []byte(`<p>edit</p>`)},
+		"ux/user/_row+post.html":  {Data: []byte(`<tr></tr>`)},
+		"ux/user/.id.delete.html": {Data: []byte(`<p>delete</p>`)},
+		"ux/blog.post.html":       {Data: []byte(`<p>post</p>`)},
+		"ux/user/_404.html":       {Data: []byte(`<p>lost</p>`)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{
+		Licenses: []License{mockSnagLicense(http.StatusTeapot)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	StockPond(&pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("edit"): {
+			Licenses: []License{mockSnagLicense(http.StatusTeapot)},
+		},
+	})
+
+	found := map[string]Route{}
+	for _, route := range Routes(&pond) {
+		found[route.File] = route
+	}
+
+	expected := map[string]Route{
+		"/ux.html": {
+			Pattern: "/", Kind: "Tuna", TemplateName: "ux",
+			PathParams: []string{}, Licenses: 1,
+		},
+		"/site.css": {
+			Pattern: "/site.css", Kind: "Clown",
+			PathParams: []string{}, Licenses: 1,
+		},
+		"/user/user.html": {
+			Pattern: "/user", Kind: "Tuna", TemplateName: "user",
+			PathParams: []string{}, Licenses: 1,
+		},
+		"/user/.id.edit.html": {
+			Pattern: "/user/{id}/edit", Kind: "Tuna", TemplateName: ".id.edit",
+			PathParams: []string{"id"}, Licenses: 2,
+		},
+		// a method after a `.` is a name like any other
+		"/user/.id.delete.html": {
+			Pattern: "/user/{id}/delete", Kind: "Tuna", TemplateName: ".id.delete",
+			PathParams: []string{"id"}, Licenses: 1,
+		},
+		"/blog.post.html": {
+			Pattern: "/blog/{post}", Kind: "Tuna", TemplateName: "blog.post",
+			PathParams: []string{"post"}, Licenses: 1,
+		},
+		"/user/_row+post.html": {
+			Pattern: "/user/_row", Methods: []string{http.MethodPost}, Kind: "Sardine", TemplateName: "_row+post",
+			PathParams: []string{}, Licenses: 1,
+		},
+	}
+	if len(found) != len(expected) {
+		t.Fatal(errNotEqual, found)
+	}
+	for file, e := range expected {
+		route := found[file]
+		if route.Pattern != e.Pattern ||
+			route.Kind != e.Kind ||
+			route.TemplateName != e.TemplateName ||
+			route.Licenses != e.Licenses ||
+			!slices.Equal(route.Methods, e.Methods) ||
+			!slices.Equal(route.PathParams, e.PathParams) ||
+			len(route.Hash) == 0 {
+			t.Fatal(errNotEqual, file, route, e)
+		}
+	}
+}
+
+func TestPathParams(t *testing.T) {
+	tests := map[string][]string{
+		"/":                   {},
+		"/user/{id}":          {"id"},
+		"/user/{id}/edit/{$}": {"id"},
+		"/a/{b}/{c...}":       {"b", "c"},
+	}
+	for pattern, expected := range tests {
+		if params := pathParams(pattern); !slices.Equal(params, expected) {
+			t.Fatal(errNotEqual, pattern, params)
+		}
+	}
+}
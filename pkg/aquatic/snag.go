@@ -0,0 +1,225 @@
+package aquatic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Snag is what happened when a fish could not be caught. Given to
+// the pond OnSnag hook, and as bait to a puffer fish as `.Snag`.
+type Snag struct {
+	// Status is the http status the response is given
+	Status int
+	// Err is what went wrong, if known
+	Err error
+	// Request is the request that got snagged
+	Request *http.Request
+}
+
+func (s *Snag) Error() string {
+	if s.Err == nil {
+		return http.StatusText(s.Status)
+	}
+	return fmt.Sprintf("%d %s: %s", s.Status, http.StatusText(s.Status), s.Err.Error())
+}
+
+func (s *Snag) Unwrap() error {
+	return s.Err
+}
+
+var (
+	// ErrBadRequest given from a lure snags with status 400
+	ErrBadRequest = errors.New("bad request")
+	// ErrUnauthorized given from a lure snags with status 401
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden given from a lure snags with status 403
+	ErrForbidden = errors.New("forbidden")
+	// ErrNotFound given from a lure snags with status 404
+	ErrNotFound = errors.New("not found")
+)
+
+// statusClientClosed is given when the client went away before a
+// fish was caught. Not standard, but known from nginx.
+const statusClientClosed = 499
+
+// snagStatuses are the status given for an error from a lure
+var snagStatuses = map[error]int{
+	ErrBadRequest:            http.StatusBadRequest,
+	ErrUnauthorized:          http.StatusUnauthorized,
+	ErrForbidden:             http.StatusForbidden,
+	ErrNotFound:              http.StatusNotFound,
+	context.DeadlineExceeded: http.StatusServiceUnavailable,
+	context.Canceled:         statusClientClosed,
+}
+
+// Redirection is given from a lure to send the request elsewhere
+// instead of catching the fish. See [Redirect].
+type Redirection struct {
+	URL    string
+	Status int
+}
+
+func (rd *Redirection) Error() string {
+	return fmt.Sprintf("redirect %d to %s", rd.Status, rd.URL)
+}
+
+// Redirect gives an error for a lure that redirects the request.
+// Status is 303 See Other if not a redirect status.
+//
+//	return nil, aquatic.Redirect("/login", http.StatusFound)
+func Redirect(url string, status int) error {
+	if status < 300 || status > 399 {
+		status = http.StatusSeeOther
+	}
+	return &Redirection{URL: url, Status: status}
+}
+
+// baitSnagged responds to an error given by a lure. A redirection
+// is followed, a snag keeps its status, a known error is given
+// its status, and anything else is the server at fault.
+func baitSnagged(w http.ResponseWriter, r *http.Request, err error) {
+	var redirection *Redirection
+	if errors.As(err, &redirection) {
+		http.Redirect(w, r, redirection.URL, redirection.Status)
+		return
+	}
+
+	var snag *Snag
+	if errors.As(err, &snag) {
+		Snagged(w, r, snag.Status, snag.Err)
+		return
+	}
+
+	for known, status := range snagStatuses {
+		if errors.Is(err, known) {
+			Snagged(w, r, status, err)
+			return
+		}
+	}
+
+	Snagged(w, r, http.StatusInternalServerError, err)
+}
+
+// snagCtxKey is where the snag handler of a fish is kept in a request context
+type snagCtxKey struct{}
+
+// snagHandler renders a snag for the fish being caught
+type snagHandler func(w http.ResponseWriter, snag *Snag)
+
+// Snagged writes an error response for a request. If the request is
+// for a fish in a pond, the pond OnSnag hook is called and the nearest
+// puffer fish for the status is rendered. Useful in a license:
+//
+//	aquatic.Snagged(w, r, http.StatusNotFound, errUserNotFound)
+func Snagged(w http.ResponseWriter, r *http.Request, status int, err error) {
+	snag := &Snag{
+		Status:  status,
+		Err:     err,
+		Request: r,
+	}
+	handle, ok := r.Context().Value(snagCtxKey{}).(snagHandler)
+	if !ok {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	handle(w, snag)
+}
+
+// withSnag gives a request the snag handler for a fish,
+// so licenses can use puffer fish too
+func withSnag[T, K any](next http.Handler, f *Fish[K], pond *Pond[T, K]) http.Handler {
+	var handle snagHandler = func(w http.ResponseWriter, snag *Snag) {
+		puff(w, f, pond, snag)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), snagCtxKey{}, handle)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// pufferStatus gives the status a puffer fish is for
+// based on its name. Such as 404 for `_404`.
+func pufferStatus(templateName string) (int, bool) {
+	s, found := strings.CutPrefix(templateName, "_")
+	if !found || len(s) != 3 {
+		return 0, false
+	}
+	status, err := strconv.Atoi(s)
+	if err != nil || status < 100 || status > 599 {
+		return 0, false
+	}
+	return status, true
+}
+
+// puff tells the pond about a snag, then renders the nearest puffer
+// fish for its status. With no puffer fish only the status is written.
+func puff[T, K any](w http.ResponseWriter, f *Fish[K], pond *Pond[T, K], snag *Snag) {
+	logger := pond.options.Logger
+	logger.Log(snag.Request.Context(), snagLevel(snag), "snagged", snagAttrs(f, snag)...)
+
+	if pond.options.OnSnag != nil && pond.options.OnSnag(w, snag) {
+		return
+	}
+
+	puffer, exists := f.puffers[snag.Status]
+	if !exists || puffer.hooked == nil {
+		w.WriteHeader(snag.Status)
+		return
+	}
+
+	// a puffer fish cannot snag again, so if its own bait
+	// fails it is rendered with what could be caught
+	pageData, err := catchBait(w, snag.Request, puffer, pond, Haul[K]{})
+	if err != nil {
+		logger.Warn("cannot bait puffer fish", append(fishAttrs(puffer), slog.Any("err", err))...)
+	}
+	pageData.Snag = snag
+
+	var b []byte
+	if bodyOnly(snag.Request) {
+		buff := bytes.NewBuffer(nil)
+		err = puffer.hooked.ExecuteTemplate(buff, puffer.templateName, pageData)
+		b = buff.Bytes()
+	} else {
+		b, err = swallow(puffer, pageData)
+	}
+	if err != nil {
+		logger.Error("cannot render puffer fish", append(fishAttrs(puffer), slog.Any("err", err))...)
+		w.WriteHeader(snag.Status)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	w.WriteHeader(snag.Status)
+	w.Write(b)
+}
+
+// nearestPuffers gives the puffer fish a fish would use for each status.
+// Puffer fish in its school win, then global ones. They are hooked as found.
+func nearestPuffers[T, K any](school []Fish[K], global map[int]*Fish[K], pond *Pond[T, K]) map[int]*Fish[K] {
+	puffers := make(map[int]*Fish[K], len(global))
+	for status, puffer := range global {
+		puffers[status] = puffer
+	}
+	for i := range school {
+		if school[i].kind != FishKindPuffer {
+			continue
+		}
+		status, _ := pufferStatus(school[i].templateName)
+		if _, err := hook(&school[i], pond); err != nil {
+			pond.options.Logger.Error("cannot hook puffer fish", append(fishAttrs(&school[i]), slog.Any("err", err))...)
+			continue
+		}
+		bobber(&school[i], pond)
+		puffers[status] = &school[i]
+	}
+	return puffers
+}
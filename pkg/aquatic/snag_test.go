@@ -0,0 +1,219 @@
+package aquatic
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"testing"
+	"testing/fstest"
+)
+
+var errMockSnag = errors.New("mock snag")
+
+// mockSnagLicense snags every catch with the status given
+func mockSnagLicense(status int) License {
+	return func(_ http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Snagged(w, r, status, errMockSnag)
+		})
+	}
+}
+
+func TestSnagged_Puffer(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{Name: "<Sally>"})
+	StockPond(pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("pond.html|user.html"): {
+			Licenses: []License{mockSnagLicense(http.StatusNotFound)},
+		},
+	})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusNotFound)
+	body := w.Body.String()
+	assertContains(t, body, `<main class="lost">404 /</main>`)
+	assertContains(t, body, `<title>Lost</title>`)
+	assertContains(t, body, `<html lang="en">`)
+
+	// nearest puffer wins, swallowed by the nearest whale shark
+	w = mockCatch(mux, http.MethodGet, "/user")
+	assertStatus(t, w, http.StatusNotFound)
+	body = w.Body.String()
+	// a puffer fish is not given the bait of the fish that snagged
+	assertContains(t, body, `<main class="user-lost">no user </main>`)
+	assertContains(t, body, `<html lang="nl">`)
+}
+
+func TestSnagged_PufferNoLure(t *testing.T) {
+	pond, err := NewPond[mockGlobal, mockLocal]("testdata/pond", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	globalCalls, localCalls := 0, 0
+	pond.Lure = func(_ http.ResponseWriter, _ *http.Request) (mockGlobal, error) {
+		globalCalls++
+		return mockGlobal{}, ErrNotFound
+	}
+	StockPond(&pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("user/user.html"): {
+			Lure: func(_ http.ResponseWriter, _ *http.Request) (mockLocal, error) {
+				localCalls++
+				return mockLocal{Name: "lure"}, nil
+			},
+		},
+	})
+	mux := CastLines(&pond, false)
+
+	// the lure that snagged is not run again for the puffer fish
+	w := mockCatch(mux, http.MethodGet, "/user")
+	assertStatus(t, w, http.StatusNotFound)
+	assertContains(t, w.Body.String(), `<main class="user-lost">no user </main>`)
+	if globalCalls != 1 || localCalls > 1 {
+		t.Fatal(errUnexpectedValue, globalCalls, localCalls)
+	}
+
+	pond.Lure = nil
+	globalCalls, localCalls = 0, 0
+	StockPond(&pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("user/user.html"): {
+			Licenses: []License{mockSnagLicense(http.StatusNotFound)},
+		},
+	})
+	mux = CastLines(&pond, false)
+
+	w = mockCatch(mux, http.MethodGet, "/user")
+	assertStatus(t, w, http.StatusNotFound)
+	assertContains(t, w.Body.String(), `<main class="user-lost">no user </main>`)
+	if localCalls != 0 {
+		t.Fatal(errUnexpectedValue, localCalls)
+	}
+}
+
+func TestSnagged_NoPuffer(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{})
+	StockPond(pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("pond.html"): {
+			Licenses: []License{mockSnagLicense(http.StatusForbidden)},
+		},
+	})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusForbidden)
+	assertNotContains(t, w.Body.String(), "<main")
+}
+
+func TestSnagged_OnSnag(t *testing.T) {
+	var snagged *Snag
+	options := NewPondOptions{
+		OnSnag: func(w http.ResponseWriter, snag *Snag) bool {
+			snagged = snag
+			if snag.Status != http.StatusTeapot {
+				return false
+			}
+			w.WriteHeader(snag.Status)
+			w.Write([]byte("handled"))
+			return true
+		},
+	}
+
+	pond := mockPond(t, options, mockLocal{})
+	StockPond(pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("pond.html"): {
+			Licenses: []License{mockSnagLicense(http.StatusTeapot)},
+		},
+		regexp.MustCompile("user.html"): {
+			Licenses: []License{mockSnagLicense(http.StatusNotFound)},
+		},
+	})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusTeapot)
+	assertContains(t, w.Body.String(), "handled")
+	if !errors.Is(snagged, errMockSnag) || snagged.Request.URL.Path != "/" {
+		t.Fatal(errUnexpectedValue, snagged)
+	}
+
+	// not handled, so still given to the puffer
+	w = mockCatch(mux, http.MethodGet, "/user")
+	assertStatus(t, w, http.StatusNotFound)
+	assertContains(t, w.Body.String(), `<main class="user-lost">`)
+	if snagged.Status != http.StatusNotFound {
+		t.Fatal(errUnexpectedValue, snagged.Status)
+	}
+}
+
+func TestSnagged_Lost(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/a/a.html":    {Data: []byte(`<p>a</p>`)},
+		"ux/_404.html":   {Data: []byte(`<p>lost {{ .Snag.Request.URL.Path }}</p>`)},
+		"ux/_500.html":   {Data: []byte(`<p>{{ .Snag.Err }}</p>`)},
+		"ux/b/b.html":    {Data: []byte(`{{ .Local.Missing }}`)},
+		"ux/b/_nav.html": {Data: []byte(`<nav></nav>`)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := CastLines(&pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/nowhere")
+	assertStatus(t, w, http.StatusNotFound)
+	assertContains(t, w.Body.String(), "<p>lost /nowhere</p>")
+
+	// puffers are not served
+	w = mockCatch(mux, http.MethodGet, "/_404")
+	assertStatus(t, w, http.StatusNotFound)
+
+	// a failed execution is given to the 500 puffer
+	w = mockCatch(mux, http.MethodGet, "/b")
+	assertStatus(t, w, http.StatusInternalServerError)
+	assertContains(t, w.Body.String(), "Missing")
+}
+
+func TestSnagged_LostRoot(t *testing.T) {
+	// a root tuna catches every path, unless it is only the root
+	for root, lost := range map[string]bool{"ux/ux.html": false, "ux/ux$.html": true} {
+		fsys := fstest.MapFS{
+			root:           {Data: []byte(`<p>home</p>`)},
+			"ux/_404.html": {Data: []byte(`<p>lost {{ .Snag.Request.URL.Path }}</p>`)},
+		}
+		pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mux := CastLines(&pond, false)
+
+		w := mockCatch(mux, http.MethodGet, "/")
+		assertStatus(t, w, http.StatusOK)
+		assertContains(t, w.Body.String(), "<p>home</p>")
+
+		w = mockCatch(mux, http.MethodGet, "/nope")
+		if !lost {
+			assertStatus(t, w, http.StatusOK)
+			assertContains(t, w.Body.String(), "<p>home</p>")
+			continue
+		}
+		assertStatus(t, w, http.StatusNotFound)
+		assertContains(t, w.Body.String(), "<p>lost /nope</p>")
+	}
+}
+
+func TestPufferStatus(t *testing.T) {
+	tests := map[string]int{
+		"_404":    http.StatusNotFound,
+		"_500":    http.StatusInternalServerError,
+		"_nav":    0,
+		"_4040":   0,
+		"404":     0,
+		"_099":    0,
+		"_layout": 0,
+	}
+	for name, expected := range tests {
+		status, _ := pufferStatus(name)
+		if status != expected {
+			t.Fatal(errUnexpectedValue, name, status)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package aquatic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	texttemplate "text/template"
+	"text/template/parse"
+)
+
+// ErrStreamedPrelude is given if a streamed tuna did not start with the
+// prelude already sent. Only if the layout is not what it was when cast.
+var ErrStreamedPrelude = errors.New("streamed tuna did not start with its prelude")
+
+// prelude finds the start of the document of a tuna, up to and including
+// its bobber, if nothing in the whale shark before the bobber needs bait.
+// It is executed with no bait to know how it is written.
+func prelude[T, K any](f *Fish[K]) []byte {
+	if len(f.bobber) == 0 {
+		// nothing for a browser to fetch early
+		return nil
+	}
+
+	var tree *parse.Tree
+	switch t := f.hooked.(type) {
+	case *template.Template:
+		if layout := t.Lookup(layoutTemplateName); layout != nil {
+			tree = layout.Tree
+		}
+	case *texttemplate.Template:
+		if layout := t.Lookup(layoutTemplateName); layout != nil {
+			tree = layout.Tree
+		}
+	}
+	if tree == nil || tree.Root == nil || !bobberFirst(tree.Root) {
+		return nil
+	}
+
+	// what comes after the bobber may need bait,
+	// so an error is expected and ignored
+	buff := bytes.NewBuffer(nil)
+	f.hooked.ExecuteTemplate(buff, layoutTemplateName, wholeTuna[T, K]{Bobber: template.HTML(f.bobber)})
+
+	i := bytes.Index(buff.Bytes(), f.bobber)
+	if i < 0 {
+		return nil
+	}
+	return buff.Bytes()[:i+len(f.bobber)]
+}
+
+// bobberFirst tells if a layout writes its bobber with only text,
+// or tackle not given bait like `{{ asset "image/smile.png" }}`, before it
+func bobberFirst(root *parse.ListNode) bool {
+	for _, node := range root.Nodes {
+		switch n := node.(type) {
+		case *parse.TextNode:
+			continue
+		case *parse.ActionNode:
+			if n.Pipe == nil || len(n.Pipe.Decl) > 0 || len(n.Pipe.Cmds) == 0 {
+				return false
+			}
+			args := n.Pipe.Cmds[0].Args
+			if len(args) == 1 {
+				if field, ok := args[0].(*parse.FieldNode); ok {
+					return len(field.Ident) == 1 && field.Ident[0] == "Bobber"
+				}
+			}
+			if !baitless(n.Pipe) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// baitless tells if a pipeline is written the same no matter the bait
+func baitless(pipe *parse.PipeNode) bool {
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.IdentifierNode, *parse.StringNode, *parse.NumberNode, *parse.BoolNode, *parse.NilNode:
+				continue
+			case *parse.PipeNode:
+				if !baitless(a) {
+					return false
+				}
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// streams tells if a tuna is written as it is rendered, its prelude
+// flushed before bait is caught. Not for a tuna with a lure, since
+// that can snag after the prelude is sent when a status cannot be given.
+func streams[T, K any](r *http.Request, f *Fish[K], pond *Pond[T, K], haul Haul[K]) bool {
+	if !pond.options.StreamHead || len(f.prelude) == 0 {
+		return false
+	}
+	if f.Lure != nil || pond.Lure != nil {
+		return false
+	}
+	return haulName(f, haul) == f.templateName && !bodyOnly(r)
+}
+
+// streamTuna flushes the prelude of a tuna so a browser starts fetching
+// its clown fish, then catches its bait and writes the rest. A snag after
+// the prelude is told to the pond and the response is cut short, so it
+// is not mistaken for the whole page.
+func streamTuna[T, K any](w http.ResponseWriter, r *http.Request, f *Fish[K], pond *Pond[T, K], haul Haul[K]) {
+	w.Header().Add("Cache-Control", "no-store")
+	w.Header().Add("Vary", bodyOnlyVary)
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.Header().Add("Content-Type", "text/html")
+
+	var out io.Writer = w
+	var zw *gzip.Writer
+	if accepts(r, "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+		zw = gzipWriters.Get().(*gzip.Writer)
+		defer gzipWriters.Put(zw)
+		zw.Reset(w)
+		out = zw
+	}
+
+	if haul.Status != 0 {
+		w.WriteHeader(haul.Status)
+	}
+	_, err := out.Write(f.prelude)
+	if err == nil && zw != nil {
+		err = zw.Flush()
+	}
+	if err != nil {
+		snagWrite(w, r, f, pond, err)
+		return
+	}
+	// not every writer can flush, then it is sent with the rest
+	http.NewResponseController(w).Flush()
+
+	pageData, err := catchBait(w, r, f, pond, haul)
+	if err != nil {
+		snagStream(w, r, f, pond, err)
+	}
+	b, err := swallow(f, pageData)
+	if err != nil {
+		snagStream(w, r, f, pond, err)
+	}
+	rest, found := bytes.CutPrefix(b, f.prelude)
+	if !found {
+		snagStream(w, r, f, pond, ErrStreamedPrelude)
+	}
+
+	_, err = out.Write(rest)
+	if err == nil && zw != nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		snagWrite(w, r, f, pond, err)
+	}
+}
+
+// snagStream tells the pond a streamed tuna snagged, then aborts the
+// response. The status is sent by then, so no puffer fish is rendered.
+func snagStream[T, K any](w http.ResponseWriter, r *http.Request, f *Fish[K], pond *Pond[T, K], err error) {
+	snagWrite(w, r, f, pond, err)
+	panic(http.ErrAbortHandler)
+}
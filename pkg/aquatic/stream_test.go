@@ -0,0 +1,151 @@
+package aquatic
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	texttemplate "text/template"
+)
+
+func mockStreamPond(t *testing.T, options NewPondOptions, layout string) *Pond[mockGlobal, mockLocal] {
+	fsys := fstest.MapFS{
+		"ux/ux.html":            {Data: []byte(`{{ define "head" }}<title>{{ .Local.Name }}</title>{{ end }}<p>{{ .Local.Name }}</p>`)},
+		"ux/_layout.html":       {Data: []byte(layout)},
+		"ux/site.css":           {Data: []byte(`body{}`)},
+		"ux/lured/lured.html":   {Data: []byte(`<p>{{ .Local.Name }}</p>`)},
+		"ux/broken/broken.html": {Data: []byte(`<p>{{ .Local.Missing }}</p>`)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pond
+}
+
+const mockStreamLayout = `<!DOCTYPE html><html><head><meta name="{{ "fish" | printf "%s" }}">{{ .Bobber }}{{ .Head }}</head><body>{{ .Body }}</body></html>`
+
+func TestStreamHead(t *testing.T) {
+	pond := mockStreamPond(t, NewPondOptions{StreamHead: true}, mockStreamLayout)
+
+	// what was sent before the bait was caught
+	var w *httptest.ResponseRecorder
+	sent := ""
+	flushed := false
+	StockPond(pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("ux.html"): {
+			Bait: func(_ *http.Request) mockLocal {
+				sent = w.Body.String()
+				flushed = w.Flushed
+				return mockLocal{Name: "slow"}
+			},
+		},
+		regexp.MustCompile("lured.html"): {
+			Lure: func(_ http.ResponseWriter, _ *http.Request) (mockLocal, error) {
+				sent = w.Body.String()
+				flushed = w.Flushed
+				return mockLocal{Name: "lured"}, nil
+			},
+		},
+	})
+	mux := CastLines(pond, false)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assertStatus(t, w, http.StatusOK)
+	expected := `<!DOCTYPE html><html><head><meta name="fish"><link rel="stylesheet" href="/site.` + hashOf([]byte(`body{}`)) + `.css">`
+	if !flushed || !strings.HasPrefix(sent, expected) || strings.Contains(sent, "<title>") {
+		t.Fatal(errUnexpectedValue, flushed, sent)
+	}
+	assertContains(t, w.Body.String(), `<title>slow</title></head><body><p>slow</p></body></html>`)
+	if len(w.Header().Get("Content-Length")) > 0 || len(w.Header().Get("ETag")) > 0 {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+	if !slices.Contains(w.Header().Values("Vary"), bodyOnlyVary) {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+
+	// the same page as rendered whole
+	whole := mockStreamPond(t, NewPondOptions{}, mockStreamLayout)
+	StockPond(whole, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("ux.html"): {
+			Bait: func(_ *http.Request) mockLocal {
+				return mockLocal{Name: "slow"}
+			},
+		},
+	})
+	if expected := mockCatch(CastLines(whole, false), http.MethodGet, "/").Body.String(); w.Body.String() != expected {
+		t.Fatal(errUnexpectedValue, w.Body.String(), expected)
+	}
+
+	// streamed gzip is whole once read
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	mux.ServeHTTP(w, r)
+	if !flushed || w.Header().Get("Content-Encoding") != "gzip" || !strings.Contains(mockUngzip(t, w.Body.Bytes()), "<p>slow</p>") {
+		t.Fatal(errUnexpectedValue, w.Header())
+	}
+
+	// a lure can snag, so it is caught first
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/lured", nil))
+	assertStatus(t, w, http.StatusOK)
+	if flushed || len(sent) > 0 {
+		t.Fatal(errUnexpectedValue, flushed, sent)
+	}
+	assertContains(t, w.Body.String(), "<p>lured</p>")
+}
+
+func TestStreamHead_BaitFirst(t *testing.T) {
+	pond := mockStreamPond(t, NewPondOptions{StreamHead: true}, `<html lang="{{ .Local.Name }}"><head>{{ .Bobber }}</head><body>{{ .Body }}</body></html>`)
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusOK)
+	if len(w.Header().Get("Content-Length")) == 0 {
+		t.Fatal(errUnexpectedValue, "layout needs bait before its bobber, so is not streamed")
+	}
+}
+
+func TestStreamHead_Snag(t *testing.T) {
+	pond := mockStreamPond(t, NewPondOptions{StreamHead: true}, mockStreamLayout)
+	mux := CastLines(pond, false)
+
+	w := httptest.NewRecorder()
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Fatal(errUnexpectedValue, r)
+		}
+		assertStatus(t, w, http.StatusOK)
+		assertContains(t, w.Body.String(), "<head>")
+		assertNotContains(t, w.Body.String(), "</html>")
+	}()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/broken", nil))
+	t.Fatal(errors.New("not aborted"))
+}
+
+func TestBobberFirst(t *testing.T) {
+	tests := map[string]bool{
+		`<head>{{ .Bobber }}{{ .Local.Name }}`:               true,
+		`<head>{{ url "user" }}{{ .Bobber }}`:                true,
+		`<head>{{ .Local.Name }}{{ .Bobber }}`:               false,
+		`<head>{{ url "user" "id" .Local.ID }}{{ .Bobber }}`: false,
+		`<head>{{ template "_nav" }}{{ .Bobber }}`:           false,
+		`<head>{{ if true }}{{ end }}{{ .Bobber }}`:          false,
+		`<head></head>`: false,
+	}
+	for layout, expected := range tests {
+		tree, err := texttemplate.New("layout").Funcs(texttemplate.FuncMap{"url": func(...any) string { return "" }}).Parse(layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bobberFirst(tree.Tree.Root) != expected {
+			t.Fatal(errUnexpectedValue, layout)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package aquatic
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrPatternConflict is given when two fish would be caught by the same request
+	ErrPatternConflict = errors.New("conflicting patterns")
+	// ErrDuplicateTemplate is given when two global fish are known by the same template name
+	ErrDuplicateTemplate = errors.New("duplicate template name")
+	// ErrInvalidPathParam is given when a path value name cannot be used by the mux
+	ErrInvalidPathParam = errors.New("invalid path value name")
+	// ErrUnknownParamType is given when a path value is given a type that cannot be checked
+	ErrUnknownParamType = errors.New("unknown path value type")
+)
+
+// pathParamName is what the mux allows a path value to be named, a go identifier
+var pathParamName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Survey looks over a pond for anything that would stop lines being cast,
+// giving back every conflicting file pair, duplicate template name,
+// invalid path value name, and unknown path value type found. Nil if
+// the pond is fit for fishing.
+func Survey[T, K any](pond *Pond[T, K]) error {
+	pond.mu.RLock()
+	defer pond.mu.RUnlock()
+
+	sortedFish, duplicates := gather(pond, noPuffers[K])
+	return survey(pond, sortedFish, duplicates)
+}
+
+// survey checks the fish gathered for a pond before they are registered.
+// Duplicates are pairs of files gather found registered the same way.
+func survey[T, K any](pond *Pond[T, K], sortedFish []*Fish[K], duplicates [][2]*Fish[K]) error {
+	errs := []error{}
+
+	for _, pair := range duplicates {
+		errs = append(errs, fmt.Errorf("%w: %s from %s and %s", ErrPatternConflict, surveyed(pair[0]), pair[0].scopedFilePath, pair[1].scopedFilePath))
+	}
+
+	valid := make([]*Fish[K], 0, len(sortedFish))
+	for _, f := range sortedFish {
+		invalid := false
+		for _, name := range pathParams(f.pattern) {
+			if pathParamName.MatchString(name) {
+				continue
+			}
+			invalid = true
+			errs = append(errs, fmt.Errorf("%w: %q in %s from %s", ErrInvalidPathParam, name, f.pattern, f.scopedFilePath))
+		}
+		for name, typ := range f.paramTypes {
+			if _, known := paramTypes[typ]; known {
+				continue
+			}
+			invalid = true
+			errs = append(errs, fmt.Errorf("%w: %s %q from %s", ErrUnknownParamType, name, typ, f.scopedFilePath))
+		}
+		if !invalid {
+			valid = append(valid, f)
+		}
+	}
+
+	errs = append(errs, conflicts(valid)...)
+	errs = append(errs, duplicateTemplates(pond)...)
+
+	return errors.Join(errs...)
+}
+
+// conflicts registers fish on a scratch mux the same as lines are cast,
+// giving back every pair of fish the mux would not allow together
+func conflicts[K any](fishes []*Fish[K]) []error {
+	methods := castMethods(fishes)
+	errs := []error{}
+
+	scratch := http.NewServeMux()
+	registered := []*Fish[K]{}
+	for _, f := range fishes {
+		if muxAllows(scratch, f, methods[f]) {
+			registered = append(registered, f)
+			continue
+		}
+
+		// find who it conflicts with, one by one
+		for _, known := range registered {
+			pair := http.NewServeMux()
+			muxAllows(pair, known, methods[known])
+			if muxAllows(pair, f, methods[f]) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("%w: %s from %s and %s from %s", ErrPatternConflict, surveyed(known), known.scopedFilePath, surveyed(f), f.scopedFilePath))
+		}
+	}
+	return errs
+}
+
+// surveyed is how a fish is described in what a survey found
+func surveyed[K any](f *Fish[K]) string {
+	return strings.TrimSpace(routeKey(f))
+}
+
+// muxAllows registers a fish with a mux, false if the mux panics
+func muxAllows[K any](mux *http.ServeMux, f *Fish[K], methods []string) (allowed bool) {
+	defer func() {
+		if recover() != nil {
+			allowed = false
+		}
+	}()
+
+	noop := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	if len(methods) == 0 {
+		mux.Handle(f.pattern, noop)
+		return true
+	}
+	for _, method := range methods {
+		mux.Handle(method+" "+f.pattern, noop)
+	}
+	return true
+}
+
+// duplicateTemplates gives the global fish known by the same template
+// name. A fish would only ever see one of them. Local fish are not
+// included, as they are meant to be chosen over global ones.
+func duplicateTemplates[T, K any](pond *Pond[T, K]) []error {
+	byName := map[string][]string{}
+	for _, f := range pond.shad {
+		if f.kind != FishKindSardine && f.kind != FishKindWhaleShark && f.kind != FishKindPuffer {
+			continue
+		}
+		byName[f.templateName] = append(byName[f.templateName], f.scopedFilePath)
+	}
+
+	names := make([]string, 0, len(byName))
+	for name, files := range byName {
+		if len(files) > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	errs := make([]error, 0, len(names))
+	for _, name := range names {
+		files := byName[name]
+		sort.Strings(files)
+		errs = append(errs, fmt.Errorf("%w: %s from %v", ErrDuplicateTemplate, name, files))
+	}
+	return errs
+}
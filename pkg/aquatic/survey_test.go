@@ -0,0 +1,99 @@
+package aquatic
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestSurvey_Fit(t *testing.T) {
+	pond := mockPond(t, NewPondOptions{}, mockLocal{})
+	err := Survey(pond)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSurvey(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ux/ux.html":        {Data: []byte(`<p>home</p>`)},
+		"ux/user.id.html":   {Data: []byte(`<p>user</p>`)},
+		"ux/user/.id.html":  {Data: []byte(`<p>user</p>`)},
+		"ux/a.x.html":       {Data: []byte(`<p>x</p>`)},
+		"ux/a/.y.html":      {Data: []byte(`<p>y</p>`)},
+		"ux/b.my-id.html":   {Data: []byte(`<p>b</p>`)},
+		"ux/e.id@uuid.html": {Data: []byte(`<p>e</p>`)},
+		"ux/c/c.html":       {Data: []byte(`<p>c</p>`)},
+		"ux/c/_card.html":   {Data: []byte(`<p>c</p>`)},
+		"ux/d/d.html":       {Data: []byte(`<p>d</p>`)},
+		"ux/d/_card.html":   {Data: []byte(`<p>d</p>`)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{GlobalSmallFish: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Survey(&pond)
+	expected := map[error][]string{
+		ErrPatternConflict:   {"/user.id.html", "/user/.id.html", "/a.x.html", "/a/.y.html"},
+		ErrInvalidPathParam:  {`"my-id"`, "/b.my-id.html"},
+		ErrUnknownParamType:  {`id "uuid"`, "/e.id@uuid.html"},
+		ErrDuplicateTemplate: {"_card from [/c/_card.html /d/_card.html]"},
+	}
+	for sentinel, contains := range expected {
+		if !errors.Is(err, sentinel) {
+			t.Fatal(errUnexpectedValue, sentinel, err)
+		}
+		for _, s := range contains {
+			if !strings.Contains(err.Error(), s) {
+				t.Fatal(errMissingContent, s, err)
+			}
+		}
+	}
+
+	// casting lines gives the same, before a mux would panic
+	defer func() {
+		r := recover()
+		castErr, ok := r.(error)
+		if !ok || !errors.Is(castErr, ErrPatternConflict) {
+			t.Fatal(errUnexpectedValue, r)
+		}
+	}()
+	CastLines(&pond, false)
+}
+
+func TestHotReload_KeepsLinesWhenUnfit(t *testing.T) {
+	dir, rel := mockTempPond(t, map[string]string{
+		"pond.html": "<p>first</p>",
+	})
+
+	pond, err := NewPond[mockGlobal, mockLocal](rel, NewPondOptions{
+		HotReload:         true,
+		HotReloadInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Drain(&pond) })
+	mux := CastLines(&pond, false)
+
+	mockWrite(t, filepath.Join(dir, "user.id.html"), "<p>user</p>")
+	mockWrite(t, filepath.Join(dir, "user", ".id.html"), "<p>user</p>")
+	mockWrite(t, filepath.Join(dir, "pond.html"), "<p>second</p>")
+	time.Sleep(100 * time.Millisecond)
+
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), "<p>first</p>")
+
+	err = os.Remove(filepath.Join(dir, "user.id.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockEventually(t, mux, "/", http.StatusOK, "<p>second</p>")
+}
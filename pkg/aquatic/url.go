@@ -0,0 +1,150 @@
+package aquatic
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrUnknownFish is given building a url for a name no fish is known by
+	ErrUnknownFish = errors.New("no fish known by that name")
+	// ErrAmbiguousFish is given building a url for a name more than one pattern is known by
+	ErrAmbiguousFish = errors.New("more than one pattern known by that name")
+	// ErrMissingParam is given building a url without a path value the pattern needs
+	ErrMissingParam = errors.New("missing path param")
+	// ErrUnknownParam is given building a url with a path value the pattern does not have
+	ErrUnknownParam = errors.New("unknown path param")
+)
+
+// chart is every pattern a tuna or sardine in a pond is caught with,
+// by its template name and by its file path without extension.
+type chart map[string][]string
+
+// chartOf charts the fish lines are cast for
+func chartOf[K any](fishes []*Fish[K]) chart {
+	c := chart{}
+	for _, f := range fishes {
+		if f.kind != FishKindTuna && f.kind != FishKindSardine {
+			continue
+		}
+		file := strings.TrimPrefix(f.scopedFilePath, "/")
+		file = strings.TrimSuffix(file, path.Ext(file))
+		c.note(f.templateName, f.pattern)
+		c.note(file, f.pattern)
+		// known without the types or marks of its path values too
+		c.note(plain(f.templateName), f.pattern)
+		c.note(plain(file), f.pattern)
+	}
+	return c
+}
+
+// note adds a pattern to a name, once
+func (c chart) note(name, pattern string) {
+	for _, known := range c[name] {
+		if known == pattern {
+			return
+		}
+	}
+	c[name] = append(c[name], pattern)
+}
+
+// url builds the url for the fish known by a name
+func (c chart) url(name string, params map[string]string) (string, error) {
+	patterns, exists := c[name]
+	if !exists {
+		return "", fmt.Errorf("%w: %s", ErrUnknownFish, name)
+	}
+	if len(patterns) > 1 {
+		return "", fmt.Errorf("%w: %s is %s, use its file path", ErrAmbiguousFish, name, strings.Join(patterns, " and "))
+	}
+	return fill(patterns[0], params)
+}
+
+// fill puts path values into a pattern, escaping them
+func fill(pattern string, params map[string]string) (string, error) {
+	used := 0
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		name := segment[1 : len(segment)-1]
+		if name == "$" {
+			segments[i] = ""
+			continue
+		}
+
+		wildcard := strings.HasSuffix(name, "...")
+		name = strings.TrimSuffix(name, "...")
+		value, exists := params[name]
+		if !exists || len(value) == 0 {
+			return "", fmt.Errorf("%w: %s for %s", ErrMissingParam, name, pattern)
+		}
+		used++
+
+		if !wildcard {
+			segments[i] = url.PathEscape(value)
+			continue
+		}
+		// the rest of a path keeps its slashes
+		parts := strings.Split(value, "/")
+		for j := range parts {
+			parts[j] = url.PathEscape(parts[j])
+		}
+		segments[i] = strings.Join(parts, "/")
+	}
+
+	if used != len(params) {
+		known := pathParams(pattern)
+		for name := range params {
+			if !slices.Contains(known, name) {
+				return "", fmt.Errorf("%w: %s for %s", ErrUnknownParam, name, pattern)
+			}
+		}
+	}
+
+	return strings.Join(segments, "/"), nil
+}
+
+// URL builds the url of a fish in a pond, by its template name or its file
+// path without extension. Path values are escaped.
+//
+//	aquatic.URL(&pond, "user.id.edit", map[string]any{"id": 3}) // /user/3/edit
+func URL[T, K any](pond *Pond[T, K], name string, params map[string]any) (string, error) {
+	c := pond.chart.Load()
+	if c == nil {
+		// lines are not cast yet
+		pond.mu.RLock()
+		sortedFish, _ := gather(pond, noPuffers[K])
+		charted := chartOf(sortedFish)
+		pond.mu.RUnlock()
+		c = &charted
+	}
+
+	values := make(map[string]string, len(params))
+	for k, v := range params {
+		values[k] = fmt.Sprint(v)
+	}
+	return c.url(name, values)
+}
+
+// urlTackle builds urls in a template from the latest chart of a pond.
+// Params are given as pairs.
+//
+//	{{ url "user.id.edit" "id" .Local.User.ID }}
+func urlTackle[T, K any](pond *Pond[T, K]) func(name string, pairs ...any) (string, error) {
+	return func(name string, pairs ...any) (string, error) {
+		if len(pairs)%2 != 0 {
+			return "", fmt.Errorf("%w: params for %s must be in pairs", ErrMissingParam, name)
+		}
+		params := make(map[string]any, len(pairs)/2)
+		for i := 0; i < len(pairs); i += 2 {
+			params[fmt.Sprint(pairs[i])] = pairs[i+1]
+		}
+		return URL(pond, name, params)
+	}
+}
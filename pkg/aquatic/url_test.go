@@ -0,0 +1,98 @@
+package aquatic
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"testing"
+	"testing/fstest"
+)
+
+func mockURLPond(t *testing.T) *Pond[mockGlobal, mockLocal] {
+	fsys := fstest.MapFS{
+		"ux/ux.html":           {Data: []byte(`<a href="{{ url "user.id.edit" "id" .Local.Name }}">edit</a>`)},
+		"ux/broken.html":       {Data: []byte(`<a href="{{ url "user.id.edit" }}">edit</a>`)},
+		"ux/user.id.edit.html": {Data: []byte(`<p>edit</p>`)},
+		"ux/user/user.html":    {Data: []byte(`<p>users</p>`)},
+		"ux/user/_card.html":   {Data: []byte(`<p>card</p>`)},
+		"ux/team/team.html":    {Data: []byte(`<p>team</p>`)},
+		"ux/team/_card.html":   {Data: []byte(`<p>card</p>`)},
+	}
+	pond, err := NewPondFS[mockGlobal, mockLocal](fsys, "ux", NewPondOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pond
+}
+
+func TestURL(t *testing.T) {
+	pond := mockURLPond(t)
+
+	tests := []struct {
+		name     string
+		params   map[string]any
+		expected string
+		err      error
+	}{
+		{"user.id.edit", map[string]any{"id": 3}, "/user/3/edit", nil},
+		{"user.id.edit", map[string]any{"id": "a b/c"}, "/user/a%20b%2Fc/edit", nil},
+		{"user", nil, "/user", nil},
+		{"user/user", nil, "/user", nil},
+		{"team/_card", nil, "/team/_card", nil},
+		{"user.id.edit", nil, "", ErrMissingParam},
+		{"user.id.edit", map[string]any{"id": ""}, "", ErrMissingParam},
+		{"user", map[string]any{"id": 3}, "", ErrUnknownParam},
+		{"nope", nil, "", ErrUnknownFish},
+		{"_card", nil, "", ErrAmbiguousFish},
+	}
+
+	check := func() {
+		for _, tt := range tests {
+			u, err := URL(pond, tt.name, tt.params)
+			if !errors.Is(err, tt.err) || u != tt.expected {
+				t.Fatal(errUnexpectedValue, tt.name, u, err)
+			}
+		}
+	}
+
+	// before and after lines are cast
+	check()
+	CastLines(pond, false)
+	check()
+}
+
+func TestURL_Tackle(t *testing.T) {
+	pond := mockURLPond(t)
+	StockPond(pond, Stock[mockGlobal, mockLocal]{
+		regexp.MustCompile("ux.html"): {
+			Bait: func(_ *http.Request) mockLocal {
+				return mockLocal{Name: "3"}
+			},
+		},
+	})
+	mux := CastLines(pond, false)
+
+	w := mockCatch(mux, http.MethodGet, "/")
+	assertStatus(t, w, http.StatusOK)
+	assertContains(t, w.Body.String(), `<a href="/user/3/edit">`)
+
+	w = mockCatch(mux, http.MethodGet, "/broken")
+	assertStatus(t, w, http.StatusInternalServerError)
+}
+
+func TestFill(t *testing.T) {
+	tests := map[string]string{
+		"/files/{path...}": "/files/a%20b/c",
+		"/files/{$}":       "/files/",
+		"/files/{path}":    "/files/a%20b%2Fc",
+	}
+	for pattern, expected := range tests {
+		u, err := fill(pattern, map[string]string{"path": "a b/c"})
+		if pattern == "/files/{$}" {
+			u, err = fill(pattern, nil)
+		}
+		if err != nil || u != expected {
+			t.Fatal(errUnexpectedValue, pattern, u, err)
+		}
+	}
+}